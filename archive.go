@@ -0,0 +1,576 @@
+package tree
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/ulikunitz/xz"
+)
+
+var archiveSuffixes = []struct {
+	suffix string
+	format string
+}{
+	{".zip", "zip"},
+	{".tar", "tar"},
+	{".tar.gz", "tar.gz"},
+	{".tgz", "tar.gz"},
+	{".tar.bz2", "tar.bz2"},
+	{".tbz2", "tar.bz2"},
+	{".tbz", "tar.bz2"},
+	{".tar.xz", "tar.xz"},
+	{".txz", "tar.xz"},
+}
+
+// ArchiveFormat returns the format of the archive judged from the name.
+// It returns one of "zip", "tar", "tar.gz", "tar.bz2" and "tar.xz",
+// or "" when the name isn't an archive.
+func ArchiveFormat(name string) string {
+	n := strings.ToLower(name)
+	for _, s := range archiveSuffixes {
+		if strings.HasSuffix(n, s.suffix) {
+			return s.format
+		}
+	}
+	return ""
+}
+
+// ArchiveBase returns the name without the extension of the archive.
+func ArchiveBase(name string) string {
+	n := strings.ToLower(name)
+	for _, s := range archiveSuffixes {
+		if strings.HasSuffix(n, s.suffix) {
+			return name[:len(name)-len(s.suffix)]
+		}
+	}
+	return name
+}
+
+// IsArchive returns that d is an archive file browsed as a directory.
+func IsArchive(d *Dir) bool {
+	return d.list != nil && !d.Virtual()
+}
+
+// newArchiveDir creates a directory which shows the objects in the archive.
+func newArchiveDir(info os.FileInfo, dirname string, context *Context) *Dir {
+	d := &Dir{
+		context:  context,
+		FileInfo: info,
+		dirname:  dirname,
+	}
+	d.list = func() (Operators, error) {
+		es, err := readArchive(d.Path())
+		if err != nil {
+			return nil, err
+		}
+		return archiveChildren(context, d.Path(), es, "", d.Path()), nil
+	}
+	return d
+}
+
+// An ArchiveFile represents a file in an archive.
+type ArchiveFile struct {
+	context *Context
+
+	os.FileInfo
+	archive string
+	name    string
+	dirname string
+	parent  *Dir
+}
+
+func (f *ArchiveFile) Context() *Context {
+	return f.context
+}
+
+func (f *ArchiveFile) Parent() *Dir {
+	return f.parent
+}
+
+func (f *ArchiveFile) SetParent(p *Dir) {
+	f.parent = p
+}
+
+func (f *ArchiveFile) Dirname() string {
+	return f.dirname
+}
+
+func (f *ArchiveFile) Path() string {
+	return filepath.Join(f.dirname, f.Name())
+}
+
+func (f *ArchiveFile) Selected() bool {
+	return f.context.isSelected(f)
+}
+
+func (f *ArchiveFile) Select() {
+	f.context.selectOperator(f)
+}
+
+func (f *ArchiveFile) Unselect() {
+	f.context.unselectOperator(f)
+}
+
+func (f *ArchiveFile) ToggleSelected() {
+	if f.Selected() {
+		f.Unselect()
+	} else {
+		f.Select()
+	}
+}
+
+// Non interface methods
+
+// Archive returns the path of the archive containing f.
+func (f *ArchiveFile) Archive() string {
+	return f.archive
+}
+
+// Open opens f to read its content.
+func (f *ArchiveFile) Open() (io.ReadCloser, error) {
+	if ArchiveFormat(f.archive) == "zip" {
+		r, err := zip.OpenReader(f.archive)
+		if err != nil {
+			return nil, err
+		}
+		for _, zf := range r.File {
+			if cleanArchiveName(zf.Name) != f.name {
+				continue
+			}
+			rc, err := zf.Open()
+			if err != nil {
+				r.Close()
+				return nil, err
+			}
+			return readCloser{rc, multiCloser{r, rc}}, nil
+		}
+		r.Close()
+		return nil, fmt.Errorf("'%s' isn't found in '%s'", f.name, f.archive)
+	}
+
+	tr, c, err := openTar(f.archive)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+		if cleanArchiveName(h.Name) == f.name {
+			return readCloser{tr, c}, nil
+		}
+	}
+	c.Close()
+	return nil, fmt.Errorf("'%s' isn't found in '%s'", f.name, f.archive)
+}
+
+// CopyTo extracts f to dstPath.
+func (f *ArchiveFile) CopyTo(dstPath string) error {
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	w, err := os.OpenFile(dstPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode().Perm()|0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+type archiveEntry struct {
+	name string
+	info os.FileInfo
+}
+
+// readArchive lists the entries in the archive.
+// The entries which point outside of the archive are ignored.
+func readArchive(p string) ([]archiveEntry, error) {
+	es := []archiveEntry{}
+	err := walkArchive(p, func(name string, info os.FileInfo, open func() (io.ReadCloser, error)) error {
+		if !isSafeArchiveName(name) {
+			return nil
+		}
+		name = cleanArchiveName(name)
+		if name == "" {
+			return nil
+		}
+		es = append(es, archiveEntry{name: name, info: info})
+		return nil
+	})
+	return es, err
+}
+
+// walkArchive calls fn with each entry in the archive in the recorded order.
+// The content of the entry can be read with open while fn is running.
+func walkArchive(p string, fn func(name string, info os.FileInfo, open func() (io.ReadCloser, error)) error) error {
+	if ArchiveFormat(p) == "zip" {
+		r, err := zip.OpenReader(p)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		for _, f := range r.File {
+			if err := fn(f.Name, f.FileInfo(), f.Open); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	tr, c, err := openTar(p)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		open := func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(tr), nil
+		}
+		if err := fn(h.Name, h.FileInfo(), open); err != nil {
+			return err
+		}
+	}
+}
+
+// isSafeArchiveName returns that the name doesn't point outside of the archive.
+func isSafeArchiveName(name string) bool {
+	for _, part := range strings.Split(filepath.ToSlash(name), "/") {
+		if part == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// archiveChildren builds the children of the directory at prefix in the archive.
+// The directories which aren't recorded in the archive are complemented.
+func archiveChildren(context *Context, archive string, es []archiveEntry, prefix, dirname string) Operators {
+	os := Operators{}
+	dirs := []string{}
+	infos := map[string]virtualInfo{}
+	for _, e := range es {
+		if !strings.HasPrefix(e.name, prefix) {
+			continue
+		}
+		rest := e.name[len(prefix):]
+		if rest == "" {
+			continue
+		}
+		if i := strings.Index(rest, "/"); i >= 0 {
+			n := rest[:i]
+			if _, ok := infos[n]; !ok {
+				dirs = append(dirs, n)
+				infos[n] = virtualInfo{name: n}
+			}
+			continue
+		}
+		if e.info.IsDir() {
+			if _, ok := infos[rest]; !ok {
+				dirs = append(dirs, rest)
+			}
+			infos[rest] = virtualInfo{name: rest, modTime: e.info.ModTime()}
+			continue
+		}
+		os = append(os, &ArchiveFile{
+			context:  context,
+			FileInfo: e.info,
+			archive:  archive,
+			name:     e.name,
+			dirname:  dirname,
+		})
+	}
+	for _, n := range dirs {
+		p := prefix + n + "/"
+		path := filepath.Join(dirname, n)
+		d := newVirtualDir(n, dirname, context, func() (Operators, error) {
+			return archiveChildren(context, archive, es, p, path), nil
+		})
+		d.FileInfo = infos[n]
+		os = append(os, d)
+	}
+	return os
+}
+
+func cleanArchiveName(name string) string {
+	name = path.Clean("/" + filepath.ToSlash(name))
+	return strings.TrimPrefix(name, "/")
+}
+
+// openTar opens the tar archive decompressing it according to the format.
+func openTar(p string) (*tar.Reader, io.Closer, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, nil, err
+	}
+	var r io.Reader = f
+	cs := multiCloser{f}
+	switch ArchiveFormat(p) {
+	case "tar.gz":
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+		r = gr
+		cs = append(cs, gr)
+	case "tar.bz2":
+		r = bzip2.NewReader(f)
+	case "tar.xz":
+		xr, err := xz.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+		r = xr
+	}
+	return tar.NewReader(r), cs, nil
+}
+
+// Compress packs operators and any children they contain into the archive at p.
+// The format is judged from the extension of p. The objects are stored
+// with the paths relative to their parent directories.
+func Compress(operators Operators, p string) (err error) {
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0664)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(p)
+		}
+	}()
+	w, err := newArchiveWriter(f, ArchiveFormat(p))
+	if err != nil {
+		f.Close()
+		return err
+	}
+	for _, o := range operators {
+		base := o.Dirname()
+		if err := filepath.Walk(o.Path(), func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(base, path)
+			if err != nil {
+				return err
+			}
+			return w.add(filepath.ToSlash(rel), path, info)
+		}); err != nil {
+			w.Close()
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// Extract unpacks the archive at p into dstDir.
+// It refuses the archive containing entries which point outside of dstDir.
+// When an object already exists at the destination of an entry,
+// conflict is called with the destination and the entry is skipped
+// unless it returns true.
+// Only directories and regular files are extracted.
+func Extract(p, dstDir string, conflict func(string) (bool, error)) error {
+	if err := walkArchive(p, func(name string, info os.FileInfo, open func() (io.ReadCloser, error)) error {
+		if !isSafeArchiveName(name) {
+			return fmt.Errorf("the entry '%s' points outside of the archive", name)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dstDir, 0775); err != nil {
+		return err
+	}
+	return walkArchive(p, func(name string, info os.FileInfo, open func() (io.ReadCloser, error)) error {
+		name = cleanArchiveName(name)
+		if name == "" {
+			return nil
+		}
+		dst := filepath.Join(dstDir, filepath.FromSlash(name))
+		if info.IsDir() {
+			return os.MkdirAll(dst, 0775)
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		if _, err := os.Lstat(dst); err == nil {
+			ok, err := conflict(dst)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return nil
+			}
+		}
+		if err := os.MkdirAll(filepath.Dir(dst), 0775); err != nil {
+			return err
+		}
+		r, err := open()
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		w, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm()|0600)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(w, r); err != nil {
+			w.Close()
+			return err
+		}
+		return w.Close()
+	})
+}
+
+// archiveWriter writes objects on file system into an archive.
+type archiveWriter struct {
+	add     func(name, path string, info os.FileInfo) error
+	closers multiCloser
+}
+
+func (w *archiveWriter) Close() error {
+	return w.closers.Close()
+}
+
+func newArchiveWriter(f *os.File, format string) (*archiveWriter, error) {
+	if format == "zip" {
+		zw := zip.NewWriter(f)
+		return &archiveWriter{
+			add: func(name, path string, info os.FileInfo) error {
+				h, err := zip.FileInfoHeader(info)
+				if err != nil {
+					return err
+				}
+				h.Name = name
+				if info.IsDir() {
+					h.Name += "/"
+				} else {
+					h.Method = zip.Deflate
+				}
+				w, err := zw.CreateHeader(h)
+				if err != nil {
+					return err
+				}
+				return copyContent(w, path, info)
+			},
+			closers: multiCloser{f, zw},
+		}, nil
+	}
+
+	var w io.Writer = f
+	cs := multiCloser{f}
+	switch format {
+	case "tar":
+	case "tar.gz":
+		gw := gzip.NewWriter(f)
+		w = gw
+		cs = append(cs, gw)
+	case "tar.xz":
+		xw, err := xz.NewWriter(f)
+		if err != nil {
+			return nil, err
+		}
+		w = xw
+		cs = append(cs, xw)
+	default:
+		return nil, fmt.Errorf("can't create the archive in the format '%s'", format)
+	}
+	tw := tar.NewWriter(w)
+	return &archiveWriter{
+		add: func(name, path string, info os.FileInfo) error {
+			var link string
+			if info.Mode()&os.ModeSymlink != 0 {
+				l, err := os.Readlink(path)
+				if err != nil {
+					return err
+				}
+				link = l
+			}
+			h, err := tar.FileInfoHeader(info, link)
+			if err != nil {
+				return err
+			}
+			h.Name = name
+			if info.IsDir() {
+				h.Name += "/"
+			}
+			if err := tw.WriteHeader(h); err != nil {
+				return err
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+			return copyContent(tw, path, info)
+		},
+		closers: append(cs, tw),
+	}, nil
+}
+
+// copyContent writes the content of the object at path into w.
+// Symbolic links are written as their targets.
+func copyContent(w io.Writer, path string, info os.FileInfo) error {
+	switch {
+	case info.Mode().IsRegular():
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(w, f)
+		return err
+	case info.Mode()&os.ModeSymlink != 0:
+		l, err := os.Readlink(path)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, l)
+		return err
+	default:
+		return nil
+	}
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// multiCloser closes the closers in reverse order.
+type multiCloser []io.Closer
+
+func (cs multiCloser) Close() error {
+	var err error
+	for i := len(cs) - 1; i >= 0; i-- {
+		if e := cs[i].Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
@@ -0,0 +1,232 @@
+package tree_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	tree "github.com/minodisk/go-tree"
+)
+
+func writeZip(t *testing.T, path string, files map[string]string) {
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := zip.NewWriter(f)
+	for name, body := range files {
+		fw, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fw.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeTarGz(t *testing.T, path string, files map[string]string) {
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gw := gzip.NewWriter(f)
+	w := tar.NewWriter(gw)
+	for name, body := range files {
+		if err := w.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(body))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-tree-archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	c := &tree.Context{Config: &tree.Config{TrashDirname: filepath.Join(dir, "trash")}}
+	if err := c.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	writeZip(t, filepath.Join(dir, "a.zip"), map[string]string{
+		"sub/a.txt": "a",
+		"b.txt":     "b",
+	})
+	writeTarGz(t, filepath.Join(dir, "c.tar.gz"), map[string]string{
+		"./x/y/c.txt": "c",
+		"../evil.txt": "evil",
+	})
+
+	d, err := tree.NewDir(dir, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.OpenRec(); err != nil {
+		t.Fatal(err)
+	}
+	if a, e := linesToString(d.Lines(0)), filepath.Base(dir)+"/\n+ a.zip/\n+ c.tar.gz/"; a != e {
+		t.Errorf("OpenRec() shouldn't open archives\nexpected:\n%s\nactual:\n%s", e, a)
+	}
+	for _, i := range []int{2, 1} {
+		o, _ := d.IndexOf(i)
+		if err := tree.ToggleRec(o); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a := linesToString(d.Lines(0))
+	e := filepath.Base(dir) + `/
+- a.zip/
+ - sub/
+  | a.txt
+ | b.txt
+- c.tar.gz/
+ - x/
+  - y/
+   | c.txt`
+	if a != e {
+		t.Errorf("archives should be opened as directories\nexpected:\n%s\nactual:\n%s", e, a)
+	}
+
+	o, ok := d.IndexOf(8)
+	if !ok {
+		t.Fatal("IndexOf(8) should return the file in the archive")
+	}
+	if err := tree.Remove(o); err != tree.ErrReadOnly {
+		t.Errorf("the file in the archive should be read-only, but Remove() returns %v", err)
+	}
+	dst := filepath.Join(dir, "c.txt")
+	if err := tree.CopyTo(o, dst); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := ioutil.ReadFile(dst); err != nil || string(b) != "c" {
+		t.Errorf("CopyTo() should extract the content of the file, but got '%s' (%v)", b, err)
+	}
+
+	o, _ = d.IndexOf(2)
+	dst = filepath.Join(dir, "sub")
+	if err := tree.CopyTo(o, dst); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := ioutil.ReadFile(filepath.Join(dst, "a.txt")); err != nil || string(b) != "a" {
+		t.Errorf("CopyTo() should extract the directory in the archive, but got '%s' (%v)", b, err)
+	}
+}
+
+func TestCompressAndExtract(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-tree-compress")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	c := &tree.Context{Config: &tree.Config{TrashDirname: filepath.Join(dir, "trash")}}
+	if err := c.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	src := filepath.Join(dir, "src")
+	if err := os.MkdirAll(filepath.Join(src, "sub"), 0775); err != nil {
+		t.Fatal(err)
+	}
+	for name, body := range map[string]string{"a.txt": "a", "sub/b.txt": "b"} {
+		if err := ioutil.WriteFile(filepath.Join(src, name), []byte(body), 0664); err != nil {
+			t.Fatal(err)
+		}
+	}
+	o, err := tree.NewOperator(src, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"src.zip", "src.tar", "src.tar.gz", "src.tar.xz"} {
+		p := filepath.Join(dir, name)
+		if err := tree.Compress(tree.Operators{o}, p); err != nil {
+			t.Fatalf("Compress() into '%s' returns error: %s", name, err)
+		}
+		dst := filepath.Join(dir, "out", tree.ArchiveBase(name)+"-"+tree.ArchiveFormat(name))
+		if err := tree.Extract(p, dst, func(string) (bool, error) { return true, nil }); err != nil {
+			t.Fatalf("Extract() from '%s' returns error: %s", name, err)
+		}
+		b, err := ioutil.ReadFile(filepath.Join(dst, "src", "sub", "b.txt"))
+		if err != nil || string(b) != "b" {
+			t.Errorf("Extract() from '%s' should restore the content, but got '%s' (%v)", name, b, err)
+		}
+
+		conflicts := 0
+		if err := tree.Extract(p, dst, func(string) (bool, error) {
+			conflicts++
+			return false, nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if conflicts != 2 {
+			t.Errorf("Extract() from '%s' should ask about 2 conflicts, but asked %d times", name, conflicts)
+		}
+	}
+
+	if err := tree.Compress(tree.Operators{o}, filepath.Join(dir, "src.zip")); err == nil {
+		t.Errorf("Compress() shouldn't overwrite the existing archive")
+	}
+
+	evil := filepath.Join(dir, "evil.tar.gz")
+	writeTarGz(t, evil, map[string]string{"../evil.txt": "evil"})
+	if err := tree.Extract(evil, filepath.Join(dir, "evil"), nil); err == nil {
+		t.Errorf("Extract() should refuse the entry pointing outside of the destination")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "evil.txt")); err == nil {
+		t.Errorf("Extract() shouldn't write outside of the destination")
+	}
+}
+
+func TestArchiveRow(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-tree-archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeZip(t, filepath.Join(dir, "a.zip"), map[string]string{"b.txt": "b"})
+	tr, clean := newTree(t, dir)
+	defer clean()
+
+	if err := tr.CreateDir(cursorAt(1), func() ([]string, error) { return []string{"d"}, nil }, func(int) error { return nil }, noRender); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.CreateFile(cursorAt(2), func() ([]string, error) { return []string{"f.txt"}, nil }, func(int) error { return nil }, noRender); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"d", "f.txt"} {
+		if _, err := os.Stat(filepath.Join(dir, p)); err != nil {
+			t.Errorf("the object should be created beside the archive: %v", err)
+		}
+	}
+
+	var opened string
+	if err := tr.Down(cursorAt(2), func(f *tree.File) error {
+		opened = f.Path()
+		return nil
+	}, noRender); err != nil {
+		t.Fatal(err)
+	}
+	if e := filepath.Join(dir, "a.zip"); opened != e || tr.Roots()[0].Path() != dir {
+		t.Errorf("Down() should open the archive as a file, but opens '%s' with the root '%s'", opened, tr.Roots()[0].Path())
+	}
+}
@@ -0,0 +1,117 @@
+package tree
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// A Bookmark is a named path.
+type Bookmark struct {
+	Name string
+	Path string
+}
+
+// Exists returns that the object at the path of b still exists.
+func (b Bookmark) Exists() bool {
+	_, err := os.Stat(b.Path)
+	return err == nil
+}
+
+// Nearest returns the path of b, or the nearest existing ancestor of it
+// when the object at the path has disappeared.
+func (b Bookmark) Nearest() string {
+	p := b.Path
+	for {
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+		parent := filepath.Dir(p)
+		if parent == p {
+			return p
+		}
+		p = parent
+	}
+}
+
+// Bookmarks is a list of Bookmark ordered by the name.
+type Bookmarks []Bookmark
+
+// LoadBookmarks reads the bookmarks from the file.
+// When the file doesn't exist, returns no bookmarks.
+func LoadBookmarks(filename string) (Bookmarks, error) {
+	bs := Bookmarks{}
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return bs, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &bs); err != nil {
+		return nil, err
+	}
+	sort.Sort(bs)
+	return bs, nil
+}
+
+// Save writes the bookmarks into the file.
+func (bs Bookmarks) Save(filename string) error {
+	b, err := json.MarshalIndent(bs, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filename, b)
+}
+
+func (bs Bookmarks) Len() int {
+	return len(bs)
+}
+
+func (bs Bookmarks) Swap(i, j int) {
+	bs[i], bs[j] = bs[j], bs[i]
+}
+
+func (bs Bookmarks) Less(i, j int) bool {
+	return bs[i].Name < bs[j].Name
+}
+
+// Names returns the names of the bookmarks.
+func (bs Bookmarks) Names() []string {
+	ns := make([]string, len(bs))
+	for i, b := range bs {
+		ns[i] = b.Name
+	}
+	return ns
+}
+
+// Find returns the bookmark named name.
+func (bs Bookmarks) Find(name string) (Bookmark, bool) {
+	for _, b := range bs {
+		if b.Name == name {
+			return b, true
+		}
+	}
+	return Bookmark{}, false
+}
+
+// Add returns the bookmarks with b.
+// The bookmark with the same name is replaced.
+func (bs Bookmarks) Add(b Bookmark) Bookmarks {
+	added := append(bs.Delete(b.Name), b)
+	sort.Sort(added)
+	return added
+}
+
+// Delete returns the bookmarks without the one named name.
+func (bs Bookmarks) Delete(name string) Bookmarks {
+	deleted := Bookmarks{}
+	for _, b := range bs {
+		if b.Name != name {
+			deleted = append(deleted, b)
+		}
+	}
+	return deleted
+}
@@ -0,0 +1,54 @@
+package tree_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	tree "github.com/minodisk/go-tree"
+)
+
+func TestBookmarks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-tree-bookmark")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "data", "bookmarks.json")
+
+	bs, err := tree.LoadBookmarks(filename)
+	if err != nil {
+		t.Fatalf("LoadBookmarks() should return no bookmarks without the file, but returns error: %s", err)
+	}
+	bs = bs.Add(tree.Bookmark{Name: "foo", Path: filepath.Join(dir, "foo")})
+	bs = bs.Add(tree.Bookmark{Name: "bar", Path: filepath.Join(dir, "gone", "deep")})
+	bs = bs.Add(tree.Bookmark{Name: "foo", Path: dir})
+	if err := bs.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	bs, err = tree.LoadBookmarks(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, e := len(bs), 2; a != e {
+		t.Fatalf("the bookmark with the same name should be replaced, expected %d bookmarks, but actual %d", e, a)
+	}
+	if a, e := bs.Names(), []string{"bar", "foo"}; a[0] != e[0] || a[1] != e[1] {
+		t.Errorf("Names() should be sorted, expected %v, but actual %v", e, a)
+	}
+	b, ok := bs.Find("bar")
+	if !ok {
+		t.Fatal("Find() should return the bookmark")
+	}
+	if b.Exists() {
+		t.Errorf("Exists() should be false for the disappeared path")
+	}
+	if a, e := b.Nearest(), dir; a != e {
+		t.Errorf("Nearest() should return the nearest existing ancestor, expected '%s', but actual '%s'", e, a)
+	}
+	if a, e := len(bs.Delete("bar")), 1; a != e {
+		t.Errorf("Delete() should remove the bookmark, expected %d bookmarks, but actual %d", e, a)
+	}
+}
@@ -0,0 +1,76 @@
+// Command finder is a file manager in the terminal built on the commands of Tree.
+//
+//	finder [directory]
+//
+// It is also the reference implementation of the callbacks
+// which the commands of Tree call to interact with the user:
+// the cursor, the rendering, the prompts, the confirmation and the choices.
+// Press '?' to show the key bindings.
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+
+	tree "github.com/minodisk/go-tree"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "finder: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	dirname := "."
+	if len(os.Args) > 1 {
+		dirname = os.Args[1]
+	}
+	ctx := &tree.Context{}
+	t, err := tree.New(dirname, ctx)
+	if err != nil {
+		return err
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !terminal.IsTerminal(fd) {
+		return fmt.Errorf("stdin isn't a terminal")
+	}
+	state, err := terminal.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer terminal.Restore(fd, state)
+
+	u := newUI(t, os.Stdin, os.Stdout)
+	u.size = func() (int, int, error) {
+		return terminal.GetSize(int(os.Stdout.Fd()))
+	}
+	// Leaves the raw mode while the editor runs in the terminal.
+	u.open = func(f *tree.File) error {
+		editor := os.Getenv("EDITOR")
+		if editor == "" {
+			editor = "vi"
+		}
+		if err := terminal.Restore(fd, state); err != nil {
+			return err
+		}
+		cmd := exec.Command(editor, f.Path())
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+		err := cmd.Run()
+		if _, rerr := terminal.MakeRaw(fd); rerr != nil {
+			return rerr
+		}
+		return err
+	}
+	fmt.Fprint(os.Stdout, enterAltScreen)
+	defer fmt.Fprint(os.Stdout, leaveAltScreen)
+	err = u.loop()
+	if ferr := ctx.FlushVisits(); err == nil {
+		err = ferr
+	}
+	return err
+}
@@ -0,0 +1,422 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	tree "github.com/minodisk/go-tree"
+)
+
+// The escape sequences of the terminal.
+const (
+	enterAltScreen = "\x1b[?1049h\x1b[?25l"
+	leaveAltScreen = "\x1b[?25h\x1b[?1049l"
+	clearScreen    = "\x1b[H\x1b[2J"
+	reverseVideo   = "\x1b[7m"
+	resetVideo     = "\x1b[0m"
+)
+
+// The keys which aren't printable.
+const (
+	keyCtrlC     = 0x03
+	keyTab       = '\t'
+	keyEnter     = '\r'
+	keyCtrlU     = 0x15
+	keyEscape    = 0x1b
+	keyBackspace = 0x7f
+	keyCtrlH     = 0x08
+)
+
+// The arrow keys are translated from the escape sequences
+// to the values out of the range of the runes.
+const (
+	keyUp = unicode.MaxRune + 1 + iota
+	keyDown
+	keyRight
+	keyLeft
+)
+
+var errCanceled = errors.New("canceled")
+var errQuit = errors.New("quit")
+
+// ui draws the tree in the terminal and reads the keys.
+// It implements tree.UI to dispatch the commands of Tree.
+type ui struct {
+	tree *tree.Tree
+	in   *bufio.Reader
+	out  io.Writer
+	size func() (int, int, error)
+	open tree.OpenFileFunc
+
+	lines   [][]byte
+	cursor  int
+	top     int
+	message string
+}
+
+func newUI(t *tree.Tree, in io.Reader, out io.Writer) *ui {
+	return &ui{
+		tree: t,
+		in:   bufio.NewReader(in),
+		out:  out,
+		size: func() (int, int, error) { return 80, 24, nil },
+		open: func(f *tree.File) error {
+			return fmt.Errorf("can't open '%s'", f.Path())
+		},
+	}
+}
+
+// uiCommands are the commands of the terminal,
+// which precede the commands of Tree with the same names.
+var uiCommands = map[string]tree.Command{
+	"cursorDown": {Name: "cursorDown", Description: "move the cursor down", Run: func(t *tree.Tree, u tree.UI) (interface{}, error) {
+		return nil, u.(*ui).moveCursor(1)
+	}},
+	"cursorUp": {Name: "cursorUp", Description: "move the cursor up", Run: func(t *tree.Tree, u tree.UI) (interface{}, error) {
+		return nil, u.(*ui).moveCursor(-1)
+	}},
+	"cursorTop": {Name: "cursorTop", Description: "move the cursor to the top", Run: func(t *tree.Tree, u tree.UI) (interface{}, error) {
+		return nil, u.SetCursor(0)
+	}},
+	"cursorBottom": {Name: "cursorBottom", Description: "move the cursor to the bottom", Run: func(t *tree.Tree, u tree.UI) (interface{}, error) {
+		return nil, u.SetCursor(len(u.(*ui).lines) - 1)
+	}},
+	"quit": {Name: "quit", Description: "quit", Run: func(t *tree.Tree, u tree.UI) (interface{}, error) {
+		return nil, errQuit
+	}},
+}
+
+// The help is added in init since it refers to uiCommands.
+func init() {
+	uiCommands["help"] = tree.Command{Name: "help", Description: "show the key bindings", Run: func(t *tree.Tree, u tree.UI) (interface{}, error) {
+		return nil, u.(*ui).help()
+	}}
+}
+
+// A binding binds a key to a command by name.
+type binding struct {
+	key     rune
+	command string
+}
+
+var bindings = []binding{
+	{'j', "cursorDown"},
+	{'k', "cursorUp"},
+	{'g', "cursorTop"},
+	{'G', "cursorBottom"},
+	{'l', "down"},
+	{'h', "up"},
+	{' ', "select"},
+	{keyTab, "toggle"},
+	{'n', "createFile"},
+	{'N', "createDir"},
+	{'r', "rename"},
+	{'m', "move"},
+	{'d', "remove"},
+	{'c', "copy"},
+	{'p', "paste"},
+	{'y', "yank"},
+	{'C', "cd"},
+	{'?', "help"},
+	{'q', "quit"},
+}
+
+// aliases are the keys run the same commands as the others.
+var aliases = map[rune]rune{
+	keyDown:      'j',
+	keyUp:        'k',
+	keyRight:     'l',
+	keyEnter:     'l',
+	keyLeft:      'h',
+	keyBackspace: 'h',
+	keyCtrlC:     'q',
+}
+
+// command returns the command named name.
+func (u *ui) command(name string) (tree.Command, bool) {
+	if c, ok := uiCommands[name]; ok {
+		return c, true
+	}
+	return u.tree.Commands().Lookup(name)
+}
+
+// dispatch runs the command named name.
+func (u *ui) dispatch(name string) error {
+	if c, ok := uiCommands[name]; ok {
+		_, err := c.Run(u.tree, u)
+		return err
+	}
+	_, err := u.tree.Dispatch(name, u)
+	return err
+}
+
+// loop renders the tree and runs the commands bound to the keys until quit.
+func (u *ui) loop() error {
+	if err := u.tree.Render(u.Render); err != nil {
+		return err
+	}
+	for {
+		k, err := u.readKey()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if a, ok := aliases[k]; ok {
+			k = a
+		}
+		for _, b := range bindings {
+			if b.key != k {
+				continue
+			}
+			u.message = ""
+			err := u.dispatch(b.command)
+			if err == errQuit {
+				return nil
+			}
+			if err != nil {
+				u.message = err.Error()
+			}
+			if err := u.draw(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// readKey reads a key translating the escape sequences of the arrow keys.
+func (u *ui) readKey() (rune, error) {
+	r, _, err := u.in.ReadRune()
+	if err != nil || r != keyEscape || u.in.Buffered() < 2 {
+		return r, err
+	}
+	if b, _ := u.in.Peek(2); b[0] != '[' {
+		return r, nil
+	}
+	seq := make([]byte, 2)
+	if _, err := io.ReadFull(u.in, seq); err != nil {
+		return 0, err
+	}
+	switch seq[1] {
+	case 'A':
+		return keyUp, nil
+	case 'B':
+		return keyDown, nil
+	case 'C':
+		return keyRight, nil
+	case 'D':
+		return keyLeft, nil
+	}
+	return keyEscape, nil
+}
+
+func (u *ui) Cursor() (int, error) {
+	return u.cursor, nil
+}
+
+// SetCursor keeps the cursor in the rendered rows.
+func (u *ui) SetCursor(c int) error {
+	if c >= len(u.lines) {
+		c = len(u.lines) - 1
+	}
+	if c < 0 {
+		c = 0
+	}
+	u.cursor = c
+	return u.draw()
+}
+
+func (u *ui) moveCursor(d int) error {
+	return u.SetCursor(u.cursor + d)
+}
+
+func (u *ui) Render(lines [][]byte) error {
+	u.lines = lines
+	return u.SetCursor(u.cursor)
+}
+
+// draw draws the rows scrolled to show the cursor and the status line.
+func (u *ui) draw() error {
+	width, height, err := u.size()
+	if err != nil {
+		return err
+	}
+	rows := height - 1
+	if u.cursor < u.top {
+		u.top = u.cursor
+	}
+	if u.cursor >= u.top+rows {
+		u.top = u.cursor - rows + 1
+	}
+	var b bytes.Buffer
+	b.WriteString(clearScreen)
+	for i := u.top; i < len(u.lines) && i < u.top+rows; i++ {
+		line := truncate(string(u.lines[i]), width)
+		if i == u.cursor {
+			line = reverseVideo + line + resetVideo
+		}
+		b.WriteString(line + "\r\n")
+	}
+	fmt.Fprintf(&b, "\x1b[%d;1H%s", height, truncate(u.message, width))
+	_, err = io.WriteString(u.out, b.String())
+	return err
+}
+
+func truncate(s string, width int) string {
+	rs := []rune(s)
+	if len(rs) > width {
+		return string(rs[:width])
+	}
+	return s
+}
+
+// prompt reads a line in the status line.
+// Escape cancels the input with errCanceled.
+func (u *ui) prompt(label, value string) (string, error) {
+	rs := []rune(value)
+	for {
+		u.message = label + string(rs)
+		if err := u.draw(); err != nil {
+			return "", err
+		}
+		r, _, err := u.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+		switch r {
+		case keyEnter, '\n':
+			u.message = ""
+			return string(rs), nil
+		case keyEscape, keyCtrlC:
+			return "", errCanceled
+		case keyBackspace, keyCtrlH:
+			if len(rs) > 0 {
+				rs = rs[:len(rs)-1]
+			}
+		case keyCtrlU:
+			rs = rs[:0]
+		default:
+			if unicode.IsPrint(r) {
+				rs = append(rs, r)
+			}
+		}
+	}
+}
+
+func (u *ui) Input(prompt, value string) (string, error) {
+	return u.prompt(prompt, value)
+}
+
+// Inputs prompts the texts separated with spaces without the defaults,
+// or prompts each text with the default.
+func (u *ui) Inputs(prompt string, values []string) ([]string, error) {
+	if len(values) == 0 {
+		s, err := u.prompt(prompt, "")
+		if err != nil {
+			return nil, err
+		}
+		return strings.Fields(s), nil
+	}
+	texts := []string{}
+	for _, v := range values {
+		t, err := u.prompt(prompt, v)
+		if err != nil {
+			return nil, err
+		}
+		texts = append(texts, t)
+	}
+	return texts, nil
+}
+
+func (u *ui) Confirm(os ...tree.Operator) (bool, error) {
+	names := []string{}
+	for _, o := range os {
+		names = append(names, filepath.Base(o.Path()))
+	}
+	u.message = fmt.Sprintf("%s: ok? (y/N)", strings.Join(names, ", "))
+	if err := u.draw(); err != nil {
+		return false, err
+	}
+	r, _, err := u.in.ReadRune()
+	if err != nil {
+		return false, err
+	}
+	u.message = ""
+	return r == 'y' || r == 'Y', nil
+}
+
+// Choose shows the numbered choices and reads the number.
+func (u *ui) Choose(prompt string, cs []string) (string, error) {
+	labels := []string{}
+	for i, c := range cs {
+		labels = append(labels, fmt.Sprintf("%d:%s", i+1, c))
+	}
+	u.message = prompt + strings.Join(labels, " ")
+	if err := u.draw(); err != nil {
+		return "", err
+	}
+	for {
+		r, _, err := u.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+		if r == keyEscape || r == keyCtrlC {
+			return "", errCanceled
+		}
+		if i, err := strconv.Atoi(string(r)); err == nil && i >= 1 && i <= len(cs) {
+			u.message = ""
+			return cs[i-1], nil
+		}
+	}
+}
+
+func (u *ui) Cancel() error {
+	return errCanceled
+}
+
+func (u *ui) OpenFile(f *tree.File) error {
+	return u.open(f)
+}
+
+// SetClipboard sets the clipboard with OSC 52,
+// which sets the clipboard through the terminal even over SSH.
+func (u *ui) SetClipboard(s string) error {
+	u.message = "yanked " + s
+	_, err := fmt.Fprintf(u.out, "\x1b]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(s)))
+	return err
+}
+
+// help shows the key bindings until a key is pressed.
+func (u *ui) help() error {
+	var b bytes.Buffer
+	b.WriteString(clearScreen)
+	for _, bd := range bindings {
+		c, _ := u.command(bd.command)
+		fmt.Fprintf(&b, "%-6s %s\r\n", keyName(bd.key), c.Description)
+	}
+	b.WriteString("\r\npress any key")
+	if _, err := io.WriteString(u.out, b.String()); err != nil {
+		return err
+	}
+	_, err := u.readKey()
+	return err
+}
+
+func keyName(k rune) string {
+	switch k {
+	case ' ':
+		return "space"
+	case keyTab:
+		return "tab"
+	}
+	return string(k)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tree "github.com/minodisk/go-tree"
+)
+
+func TestUI(t *testing.T) {
+	dir, err := ioutil.TempDir("", "finder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	root := filepath.Join(dir, "root")
+	if err := os.MkdirAll(filepath.Join(root, "b"), 0775); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{"a.txt", "z.txt"} {
+		if err := ioutil.WriteFile(filepath.Join(root, f), []byte{}, 0664); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tr, err := tree.New(root, &tree.Context{Config: &tree.Config{
+		TrashDirname:   filepath.Join(dir, "trash"),
+		VisitsFilename: filepath.Join(dir, "visits.json"),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := strings.Join([]string{
+		"jj",             // a.txt
+		"r\x15c.txt\r",   // renames a.txt to c.txt
+		"\x1b[B",         // z.txt with the arrow key
+		"r\x1b",          // cancels renaming z.txt
+		"c", "gj\t", "p", // pastes z.txt into the opened b
+		"p2\x15d.txt\r", // pastes z.txt into b again renaming on the conflict
+		"G", "dn",       // doesn't remove z.txt
+		"d", "y", // removes z.txt
+		"y",      // yanks the path of c.txt
+		"q", "j", // quits before j
+	}, "")
+	var out bytes.Buffer
+	u := newUI(tr, strings.NewReader(keys), &out)
+	if err := u.loop(); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, exists := range map[string]bool{"a.txt": false, "c.txt": true, "z.txt": false, "b/z.txt": true, "b/d.txt": true} {
+		if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(name))); (err == nil) != exists {
+			t.Errorf("%s should exist: %t", name, exists)
+		}
+	}
+	if a, e := linesToString(u.lines), "root/\n- b/\n | d.txt\n | z.txt\n| c.txt"; a != e {
+		t.Errorf("the tree should be rendered\nexpected:\n%s\nactual:\n%s", e, a)
+	}
+	if u.cursor != 4 {
+		t.Errorf("the cursor should be kept in the rows, but at %d", u.cursor)
+	}
+	a := out.String()
+	if e := "yanked " + filepath.Join(root, "c.txt"); !strings.Contains(a, e) {
+		t.Errorf("the status line should show '%s'", e)
+	}
+	if !strings.Contains(a, "canceled") {
+		t.Errorf("the canceled prompt should be shown in the status line")
+	}
+}
+
+func linesToString(lines [][]byte) string {
+	ls := []string{}
+	for _, l := range lines {
+		ls = append(ls, string(l))
+	}
+	return strings.Join(ls, "\n")
+}
@@ -0,0 +1,35 @@
+// Command gotree-server serves the commands of Tree over JSON-RPC 2.0 on stdio.
+//
+//	gotree-server [directory]
+//
+// See the package rpc for the protocol.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	tree "github.com/minodisk/go-tree"
+	"github.com/minodisk/go-tree/rpc"
+)
+
+func main() {
+	dirname := "."
+	if len(os.Args) > 1 {
+		dirname = os.Args[1]
+	}
+	ctx := &tree.Context{}
+	t, err := tree.New(dirname, ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gotree-server: %s\n", err)
+		os.Exit(1)
+	}
+	err = rpc.NewServer(t, os.Stdin, os.Stdout).Serve()
+	if ferr := ctx.FlushVisits(); err == nil {
+		err = ferr
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gotree-server: %s\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// A gitignore tells the paths ignored by the .gitignore files
+// in the directory and its ancestors up to the root of the repository.
+type gitignore struct {
+	rules map[string][]ignoreRule
+}
+
+// An ignoreRule is a pattern in a .gitignore file.
+type ignoreRule struct {
+	base     string
+	re       *regexp.Regexp
+	anchored bool
+	dirOnly  bool
+	negate   bool
+}
+
+func newGitignore() *gitignore {
+	return &gitignore{rules: map[string][]ignoreRule{}}
+}
+
+// Ignored returns that path is ignored.
+// The last matching rule wins, and the rules in the deeper directories
+// take precedence like git.
+func (g *gitignore) Ignored(path string, dir bool) bool {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	ignored := false
+	for _, r := range g.rulesOf(filepath.Dir(path)) {
+		if r.match(path, dir) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+// rulesOf returns the rules applied to the objects in dirname,
+// ordered from the root of the repository.
+func (g *gitignore) rulesOf(dirname string) []ignoreRule {
+	if rs, ok := g.rules[dirname]; ok {
+		return rs
+	}
+	rs := []ignoreRule{}
+	if _, err := os.Stat(filepath.Join(dirname, ".git")); err != nil {
+		if parent := filepath.Dir(dirname); parent != dirname {
+			rs = append(rs, g.rulesOf(parent)...)
+		}
+	}
+	rs = append(rs, readIgnoreRules(dirname)...)
+	g.rules[dirname] = rs
+	return rs
+}
+
+func readIgnoreRules(dirname string) []ignoreRule {
+	f, err := os.Open(filepath.Join(dirname, ".gitignore"))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	rs := []ignoreRule{}
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if r, ok := parseIgnoreRule(dirname, s.Text()); ok {
+			rs = append(rs, r)
+		}
+	}
+	return rs
+}
+
+func parseIgnoreRule(base, line string) (ignoreRule, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+	r := ignoreRule{base: base}
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if strings.Contains(line, "/") {
+		r.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return ignoreRule{}, false
+	}
+	re, err := regexp.Compile(globToRegexp(line))
+	if err != nil {
+		return ignoreRule{}, false
+	}
+	r.re = re
+	return r, true
+}
+
+func (r ignoreRule) match(path string, dir bool) bool {
+	if r.dirOnly && !dir {
+		return false
+	}
+	if !r.anchored {
+		return r.re.MatchString(filepath.Base(path))
+	}
+	rel, err := filepath.Rel(r.base, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	return r.re.MatchString(filepath.ToSlash(rel))
+}
+
+// globToRegexp converts the pattern of .gitignore to the regular expression.
+func globToRegexp(p string) string {
+	var b bytes.Buffer
+	b.WriteString("^")
+	for i := 0; i < len(p); {
+		switch {
+		case strings.HasPrefix(p[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 3
+		case strings.HasPrefix(p[i:], "**"):
+			b.WriteString(".*")
+			i += 2
+		case p[i] == '*':
+			b.WriteString("[^/]*")
+			i++
+		case p[i] == '?':
+			b.WriteString("[^/]")
+			i++
+		case p[i] == '[':
+			j := strings.IndexByte(p[i+1:], ']')
+			if j < 0 {
+				b.WriteString(regexp.QuoteMeta(p[i:]))
+				i = len(p)
+				continue
+			}
+			class := p[i+1 : i+1+j]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += j + 2
+		case p[i] == '\\' && i+1 < len(p):
+			b.WriteString(regexp.QuoteMeta(p[i+1 : i+2]))
+			i += 2
+		default:
+			b.WriteString(regexp.QuoteMeta(p[i : i+1]))
+			i++
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGitignore(t *testing.T) {
+	root := writeFixtures(t, map[string]string{
+		".git/HEAD":      "",
+		".gitignore":     "# comment\n*.log\n!keep.log\nbuild/\n/top.txt\ndocs/**/*.md\nfile[0-9].txt\n",
+		"sub/.gitignore": "local.txt\n!other.log\n",
+	})
+	defer os.RemoveAll(filepath.Dir(root))
+
+	g := newGitignore()
+	for _, c := range []struct {
+		path    string
+		dir     bool
+		ignored bool
+	}{
+		{"a.log", false, true},
+		{"keep.log", false, false},
+		{"sub/deep/a.log", false, true},
+		{"sub/other.log", false, false},
+		{"build", true, true},
+		{"build", false, false},
+		{"top.txt", false, true},
+		{"sub/top.txt", false, false},
+		{"docs/a/b/c.md", false, true},
+		{"docs/c.md", false, true},
+		{"c.md", false, false},
+		{"file1.txt", false, true},
+		{"filea.txt", false, false},
+		{"sub/local.txt", false, true},
+		{"local.txt", false, false},
+	} {
+		if a := g.Ignored(filepath.Join(root, filepath.FromSlash(c.path)), c.dir); a != c.ignored {
+			t.Errorf("%s (dir: %t) should be ignored: %t, but %t", c.path, c.dir, c.ignored, a)
+		}
+	}
+}
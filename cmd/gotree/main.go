@@ -0,0 +1,185 @@
+// Command gotree prints the directory trees rendered by go-tree.
+//
+//	gotree [flags] [directory ...]
+//
+// It prints the tree of the current directory when no directory is given.
+// The output ends with the numbers of the directories and the files,
+// so that it can be used in scripts and CI logs like the tree command.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tree "github.com/minodisk/go-tree"
+)
+
+// The formats of the output.
+const (
+	formatText  = "text"
+	formatBox   = "box"
+	formatASCII = "ascii"
+	formatJSON  = "json"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		if err != flag.ErrHelp {
+			fmt.Fprintf(os.Stderr, "gotree: %s\n", err)
+		}
+		os.Exit(2)
+	}
+}
+
+func run(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("gotree", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: gotree [flags] [directory ...]")
+		flags.PrintDefaults()
+	}
+	depth := flags.Int("L", 0, "descend only `level` directories deep (0 means no limit)")
+	all := flags.Bool("a", false, "show the hidden files whose names begin with '.'")
+	gitignore := flags.Bool("gitignore", false, "hide the files ignored by .gitignore")
+	sortBy := flags.String("sort", tree.SortName, "sort the objects by `name`, size or mtime")
+	reverse := flags.Bool("r", false, "reverse the order of the sort")
+	dirsOnly := flags.Bool("d", false, "list the directories only")
+	include := flags.String("P", "", "list only the files matching the `pattern`, alternated with '|'")
+	exclude := flags.String("I", "", "don't list the objects matching the `pattern`, alternated with '|'")
+	format := flags.String("f", formatText, "output `format`: text, box, ascii or json")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	dirnames := flags.Args()
+	if len(dirnames) == 0 {
+		dirnames = []string{"."}
+	}
+
+	style, ok := map[string]string{
+		formatText:  tree.RenderIndent,
+		formatBox:   tree.RenderBox,
+		formatASCII: tree.RenderASCII,
+		formatJSON:  tree.RenderIndent,
+	}[*format]
+	if !ok {
+		return fmt.Errorf("unknown format '%s'", *format)
+	}
+	ctx := &tree.Context{Config: &tree.Config{
+		RenderStyle: style,
+		SortBy:      *sortBy,
+		SortReverse: *reverse,
+		// Archives are listed as files like the tree command.
+		ArchivesAsFiles: true,
+	}}
+	if err := ctx.Init(); err != nil {
+		return err
+	}
+	f := &filter{
+		all:      *all,
+		dirsOnly: *dirsOnly,
+		include:  patterns(*include),
+		exclude:  patterns(*exclude),
+	}
+	if *gitignore {
+		f.ignore = newGitignore()
+	}
+	ctx.Filter = f.match
+
+	roots := []*tree.Dir{}
+	for _, dirname := range dirnames {
+		d, err := tree.NewDir(dirname, ctx)
+		if err != nil {
+			return err
+		}
+		if err := d.OpenDepth(*depth); err != nil {
+			return err
+		}
+		roots = append(roots, d)
+	}
+
+	if *format == formatJSON {
+		return tree.Export(stdout, roots, tree.FormatJSON, tree.ExportOptions{})
+	}
+	dirs, files := 0, 0
+	for _, d := range roots {
+		for _, line := range d.Rows() {
+			if _, err := fmt.Fprintf(stdout, "%s\n", line); err != nil {
+				return err
+			}
+		}
+		for _, o := range d.All()[1:] {
+			if isDir(o) {
+				dirs++
+			} else {
+				files++
+			}
+		}
+	}
+	_, err := fmt.Fprintf(stdout, "\n%s, %s\n", plural(dirs, "directory", "directories"), plural(files, "file", "files"))
+	return err
+}
+
+// A filter decides the objects listed with the flags.
+type filter struct {
+	all      bool
+	dirsOnly bool
+	include  []string
+	exclude  []string
+	ignore   *gitignore
+}
+
+func (f *filter) match(o tree.Operator) bool {
+	name := o.Name()
+	dir := isDir(o)
+	if !f.all && strings.HasPrefix(name, ".") {
+		return false
+	}
+	if f.dirsOnly && !dir {
+		return false
+	}
+	if matchAny(f.exclude, name) {
+		return false
+	}
+	if !dir && len(f.include) > 0 && !matchAny(f.include, name) {
+		return false
+	}
+	if f.ignore != nil && f.ignore.Ignored(o.Path(), dir) {
+		return false
+	}
+	return true
+}
+
+// isDir returns that o is a directory.
+// Archives are files here, since they are listed with ArchivesAsFiles.
+func isDir(o tree.Operator) bool {
+	_, ok := o.(*tree.Dir)
+	return ok
+}
+
+// patterns splits the patterns alternated with '|'.
+func patterns(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "|")
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := filepath.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func plural(n int, singular, plural string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, singular)
+	}
+	return fmt.Sprintf("%d %s", n, plural)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFixtures(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "gotree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := filepath.Join(dir, "root")
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0775); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0664); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestRun(t *testing.T) {
+	root := writeFixtures(t, map[string]string{
+		"a/aa/deep.go":  "",
+		"a/x.go":        "12345",
+		"a/y.go":        "1",
+		"a/x_test.go":   "",
+		"b/.hidden":     "",
+		"build/out.log": "",
+		"z.txt":         "",
+		".gitignore":    "build/\n",
+		".git/HEAD":     "",
+	})
+	defer os.RemoveAll(filepath.Dir(root))
+
+	for _, c := range []struct {
+		args []string
+		e    string
+	}{
+		{[]string{"-f", "box", "-L", "2", "-I", "*_test.go"}, `root/
+├── - a/
+│   ├── + aa/
+│   ├── x.go
+│   └── y.go
+├── - b/
+├── - build/
+│   └── out.log
+└── z.txt
+
+4 directories, 4 files
+`},
+		{[]string{"-gitignore", "-P", "*.go", "-sort", "size", "-r"}, `root/
+- a/
+ - aa/
+  | deep.go
+ | x.go
+ | y.go
+ | x_test.go
+- b/
+
+3 directories, 4 files
+`},
+		{[]string{"-d", "-a", "-gitignore"}, `root/
+- .git/
+- a/
+ - aa/
+- b/
+
+4 directories, 0 files
+`},
+	} {
+		var out bytes.Buffer
+		if err := run(append(c.args, root), &out, ioutil.Discard); err != nil {
+			t.Fatal(err)
+		}
+		if a := out.String(); a != c.e {
+			t.Errorf("%v should print\n%s\nbut prints\n%s", c.args, c.e, a)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := run([]string{"-f", "json", "-d", root}, &out, ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	var js []map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &js); err != nil {
+		t.Fatalf("json format should print JSON: %s\n%s", err, out.String())
+	}
+	if len(js) != 2 || js[1]["directories"] != 4.0 || js[1]["files"] != 0.0 {
+		t.Errorf("json format should print the report, but prints %s", out.String())
+	}
+
+	archive := writeFixtures(t, map[string]string{"d/a.txt": "", "p.zip": ""})
+	defer os.RemoveAll(filepath.Dir(archive))
+	out.Reset()
+	if err := run([]string{archive}, &out, ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if a, e := out.String(), "root/\n- d/\n | a.txt\n| p.zip\n\n1 directory, 2 files\n"; a != e {
+		t.Errorf("the archive should be listed as a file\nexpected:\n%s\nactual:\n%s", e, a)
+	}
+
+	for _, args := range [][]string{{"-f", "xml"}, {"-sort", "color"}} {
+		if err := run(append(args, root), ioutil.Discard, ioutil.Discard); err == nil {
+			t.Errorf("%v should fail", args)
+		}
+	}
+}
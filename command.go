@@ -0,0 +1,378 @@
+package tree
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// ErrNoSelection is returned when the command needing the selection
+// is dispatched without the selected objects.
+var ErrNoSelection = errors.New("no object is selected")
+
+// A UI provides the callbacks of the commands dispatched by name.
+// Input, Inputs and Choose should return an error when the user cancels.
+type UI interface {
+	Cursor() (int, error)
+	SetCursor(int) error
+	Render([][]byte) error
+	Confirm(...Operator) (bool, error)
+	Input(prompt, value string) (string, error)
+	Inputs(prompt string, values []string) ([]string, error)
+	Choose(prompt string, choices []string) (string, error)
+	Cancel() error
+	OpenFile(*File) error
+	SetClipboard(string) error
+}
+
+// A Command is a command of Tree dispatched by name,
+// so that key maps and config files can refer to it.
+type Command struct {
+	Name        string
+	Description string
+	// Mutates is true when the command changes the objects on the file system.
+	Mutates bool
+	// UsesSelection is true when the command acts on the selected objects
+	// instead of the object at the cursor when any are selected.
+	UsesSelection bool
+	// NeedsSelection is true when the command fails without the selected objects.
+	// The commands falling back to the object at the cursor don't need them.
+	NeedsSelection bool
+	// Run runs the command with the callbacks of ui.
+	// The result is returned by Dispatch.
+	Run func(t *Tree, ui UI) (interface{}, error)
+}
+
+// Commands is the registry of the commands.
+type Commands struct {
+	commands map[string]Command
+}
+
+// NewCommands creates the registry of the built-in commands.
+func NewCommands() *Commands {
+	cs := &Commands{commands: map[string]Command{}}
+	for _, c := range builtinCommands {
+		cs.commands[c.Name] = c
+	}
+	return cs
+}
+
+// Register adds the command.
+// Fails when a command with the same name is registered.
+func (cs *Commands) Register(c Command) error {
+	if c.Name == "" || c.Run == nil {
+		return errors.New("the command needs the name and Run")
+	}
+	if _, ok := cs.commands[c.Name]; ok {
+		return fmt.Errorf("the command '%s' is already registered", c.Name)
+	}
+	cs.commands[c.Name] = c
+	return nil
+}
+
+// Lookup returns the command named name.
+func (cs *Commands) Lookup(name string) (Command, bool) {
+	c, ok := cs.commands[name]
+	return c, ok
+}
+
+// Names returns the sorted names of the commands.
+func (cs *Commands) Names() []string {
+	names := make([]string, 0, len(cs.commands))
+	for name := range cs.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Commands returns the registry of the commands dispatched by Dispatch.
+func (t *Tree) Commands() *Commands {
+	return t.context.Commands
+}
+
+// Dispatch runs the command named name with the callbacks of ui.
+func (t *Tree) Dispatch(name string, ui UI) (interface{}, error) {
+	c, ok := t.context.Commands.Lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown command '%s'", name)
+	}
+	if c.NeedsSelection && !t.HasSelected() {
+		return nil, ErrNoSelection
+	}
+	return c.Run(t, ui)
+}
+
+// The adapters from UI to the callbacks of the commands.
+
+func textOf(ui UI, prompt string) TextFunc {
+	return func() (string, error) {
+		return ui.Input(prompt, "")
+	}
+}
+
+func textsOf(ui UI, prompt string) TextsFunc {
+	return func() ([]string, error) {
+		return ui.Inputs(prompt, []string{})
+	}
+}
+
+// operatorTextOf prompts with the name of the object as the default.
+// The prompt is formatted with the name.
+func operatorTextOf(ui UI, prompt string) OperatorTextFunc {
+	return func(o Operator) (string, error) {
+		return ui.Input(fmt.Sprintf(prompt, o.Name()), o.Name())
+	}
+}
+
+// operatorsTextOf prompts formatted with the number of the objects.
+func operatorsTextOf(ui UI, prompt string) OperatorsTextFunc {
+	return func(os Operators) (string, error) {
+		return ui.Input(fmt.Sprintf(prompt, len(os)), "")
+	}
+}
+
+// operatorsTextsOf prompts with the names of the objects as the defaults.
+func operatorsTextsOf(ui UI, prompt string) OperatorsTextsFunc {
+	return func(os Operators) ([]string, error) {
+		names := make([]string, len(os))
+		for i, o := range os {
+			names[i] = o.Name()
+		}
+		return ui.Inputs(prompt, names)
+	}
+}
+
+func chooseOf(ui UI, prompt string) ChooseFunc {
+	return func(cs []string) (string, error) {
+		return ui.Choose(prompt, cs)
+	}
+}
+
+// rangeOf prompts for the row, and returns the range
+// between it and the row at the cursor.
+func rangeOf(ui UI, prompt string) SelectedRangeFunc {
+	return func() (Range, error) {
+		c, err := ui.Cursor()
+		if err != nil {
+			return Range{}, err
+		}
+		s, err := ui.Input(prompt, strconv.Itoa(c))
+		if err != nil {
+			return Range{}, err
+		}
+		row, err := strconv.Atoi(s)
+		if err != nil {
+			return Range{}, err
+		}
+		if row < c {
+			return Range{Start: row, End: c}, nil
+		}
+		return Range{Start: c, End: row}, nil
+	}
+}
+
+// compareMethods are the choices of CompareMethod in the order of the prompt.
+var compareMethods = []struct {
+	name   string
+	method CompareMethod
+}{
+	{"modtime", CompareModTime},
+	{"content", CompareContent},
+}
+
+// compareMethodOf prompts for the method to compare the directories.
+func compareMethodOf(ui UI, prompt string) (CompareMethod, error) {
+	cs := make([]string, len(compareMethods))
+	for i, m := range compareMethods {
+		cs[i] = m.name
+	}
+	c, err := ui.Choose(prompt, cs)
+	if err != nil {
+		return 0, err
+	}
+	for _, m := range compareMethods {
+		if m.name == c {
+			return m.method, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown compare method '%s'", c)
+}
+
+var builtinCommands = []Command{
+	{Name: "render", Description: "render the tree", Run: func(t *Tree, ui UI) (interface{}, error) {
+		return nil, t.Render(ui.Render)
+	}},
+	{Name: "scan", Description: "read the directories again and render the tree", Run: func(t *Tree, ui UI) (interface{}, error) {
+		return nil, t.ScanAndRender(ui.Render)
+	}},
+	{Name: "cd", Description: "change the root under the cursor", Run: func(t *Tree, ui UI) (interface{}, error) {
+		return nil, t.CD(ui.Cursor, textOf(ui, "cd: "), ui.Render)
+	}},
+	{Name: "addRoot", Description: "add a root directory", Run: func(t *Tree, ui UI) (interface{}, error) {
+		return nil, t.AddRoot(textOf(ui, "add root: "), ui.Render)
+	}},
+	{Name: "removeRoot", Description: "remove the root at the cursor", Run: func(t *Tree, ui UI) (interface{}, error) {
+		return nil, t.RemoveRoot(ui.Cursor, ui.Render)
+	}},
+	{Name: "back", Description: "go back in the history of the roots", Run: func(t *Tree, ui UI) (interface{}, error) {
+		return nil, t.Back(ui.Cursor, ui.SetCursor, ui.Render)
+	}},
+	{Name: "forward", Description: "go forward in the history of the roots", Run: func(t *Tree, ui UI) (interface{}, error) {
+		return nil, t.Forward(ui.Cursor, ui.SetCursor, ui.Render)
+	}},
+	{Name: "up", Description: "go up to the parent directory", Run: func(t *Tree, ui UI) (interface{}, error) {
+		return nil, t.Up(ui.Cursor, ui.Render)
+	}},
+	{Name: "down", Description: "set the directory as the root or open the file", Run: func(t *Tree, ui UI) (interface{}, error) {
+		return nil, t.Down(ui.Cursor, ui.OpenFile, ui.Render)
+	}},
+	{Name: "home", Description: "set the home directory as the root", Run: func(t *Tree, ui UI) (interface{}, error) {
+		return nil, t.Home(ui.Cursor, ui.Render)
+	}},
+	{Name: "root", Description: "set the root directory of the file system as the root", Run: func(t *Tree, ui UI) (interface{}, error) {
+		return nil, t.Root(ui.Cursor, ui.Render)
+	}},
+	{Name: "project", Description: "set the project directory as the root", Run: func(t *Tree, ui UI) (interface{}, error) {
+		return nil, t.Project(ui.Cursor, ui.Render)
+	}},
+	{Name: "trash", Description: "set the trash as the root", Run: func(t *Tree, ui UI) (interface{}, error) {
+		return nil, t.Trash(ui.Cursor, ui.Render)
+	}},
+	{Name: "trashView", Description: "set the view of the trash as the root", Run: func(t *Tree, ui UI) (interface{}, error) {
+		return nil, t.TrashView(ui.Cursor, ui.Render)
+	}},
+	{Name: "reveal", Description: "open the directories to the path", Run: func(t *Tree, ui UI) (interface{}, error) {
+		p, err := ui.Input("reveal: ", "")
+		if err != nil {
+			return nil, err
+		}
+		n, err := t.Reveal(p, RevealParent, ui.Render)
+		if err != nil {
+			return nil, err
+		}
+		return n, ui.SetCursor(n)
+	}},
+	{Name: "select", Description: "select or unselect the object", Run: func(t *Tree, ui UI) (interface{}, error) {
+		return nil, t.Select(ui.Cursor, ui.SetCursor, ui.Render)
+	}},
+	{Name: "reverseSelected", Description: "reverse the selection of the shown objects", Run: func(t *Tree, ui UI) (interface{}, error) {
+		return nil, t.ReverseSelected(ui.Render)
+	}},
+	{Name: "selectRange", Description: "select the objects between the cursor and the row", Run: func(t *Tree, ui UI) (interface{}, error) {
+		return t.SelectRange(rangeOf(ui, "select to row: "), ui.Render)
+	}},
+	{Name: "selectAll", Description: "select the objects in the directory", Run: func(t *Tree, ui UI) (interface{}, error) {
+		return t.SelectAll(ui.Cursor, ui.Render)
+	}},
+	{Name: "clearSelection", Description: "unselect all objects", NeedsSelection: true, Run: func(t *Tree, ui UI) (interface{}, error) {
+		return t.ClearSelection(ui.Render)
+	}},
+	{Name: "hiddenSelecteds", Description: "return the paths of the selected objects not shown", NeedsSelection: true, Run: func(t *Tree, ui UI) (interface{}, error) {
+		paths := []string{}
+		err := t.HiddenSelecteds(func(os Operators) error {
+			for _, o := range os {
+				paths = append(paths, o.Path())
+			}
+			return nil
+		})
+		return paths, err
+	}},
+	{Name: "selectGlob", Description: "select the shown objects matching the glob pattern", Run: func(t *Tree, ui UI) (interface{}, error) {
+		return t.SelectGlob(ui.Cursor, textOf(ui, "glob: "), SelectShown, ui.Render)
+	}},
+	{Name: "selectRegexp", Description: "select the shown objects matching the regular expression", Run: func(t *Tree, ui UI) (interface{}, error) {
+		return t.SelectRegexp(ui.Cursor, textOf(ui, "regexp: "), SelectShown, ui.Render)
+	}},
+	{Name: "toggle", Description: "open or close the directory", Run: func(t *Tree, ui UI) (interface{}, error) {
+		return nil, t.Toggle(ui.Cursor, ui.Render)
+	}},
+	{Name: "toggleRec", Description: "open or close the directory recursively", Run: func(t *Tree, ui UI) (interface{}, error) {
+		return nil, t.ToggleRec(ui.Cursor, ui.Render)
+	}},
+	{Name: "createDir", Description: "create directories", Mutates: true, Run: func(t *Tree, ui UI) (interface{}, error) {
+		return nil, t.CreateDir(ui.Cursor, textsOf(ui, "new directories: "), ui.SetCursor, ui.Render)
+	}},
+	{Name: "createFile", Description: "create files", Mutates: true, Run: func(t *Tree, ui UI) (interface{}, error) {
+		return nil, t.CreateFile(ui.Cursor, textsOf(ui, "new files: "), ui.SetCursor, ui.Render)
+	}},
+	{Name: "rename", Description: "rename the objects", Mutates: true, UsesSelection: true, Run: func(t *Tree, ui UI) (interface{}, error) {
+		return nil, t.Rename(ui.Cursor, operatorTextOf(ui, "rename '%s' to: "), operatorsTextsOf(ui, "rename to: "), ui.Cancel, ui.SetCursor, ui.Render)
+	}},
+	{Name: "move", Description: "move the objects", Mutates: true, UsesSelection: true, Run: func(t *Tree, ui UI) (interface{}, error) {
+		return nil, t.Move(ui.Cursor, operatorsTextOf(ui, "move %d objects to: "), ui.Cancel, ui.SetCursor, ui.Render)
+	}},
+	{Name: "remove", Description: "move the objects to the trash", Mutates: true, UsesSelection: true, Run: func(t *Tree, ui UI) (interface{}, error) {
+		return nil, t.Remove(ui.Cursor, ui.Confirm, ui.Cancel, ui.SetCursor, ui.Render)
+	}},
+	{Name: "removePermanently", Description: "remove the objects permanently", Mutates: true, UsesSelection: true, Run: func(t *Tree, ui UI) (interface{}, error) {
+		return nil, t.RemovePermanently(ui.Cursor, ui.Confirm, ui.Cancel, ui.SetCursor, ui.Render)
+	}},
+	{Name: "restore", Description: "restore the objects in the trash", Mutates: true, UsesSelection: true, Run: func(t *Tree, ui UI) (interface{}, error) {
+		return nil, t.Restore(ui.Cursor, ui.Confirm, chooseOf(ui, "restore: "), operatorTextOf(ui, "restore '%s' as: "), operatorTextOf(ui, "restore '%s' to: "), ui.Cancel, ui.SetCursor, ui.Render)
+	}},
+	{Name: "emptyTrash", Description: "remove all objects in the trash", Mutates: true, Run: func(t *Tree, ui UI) (interface{}, error) {
+		return nil, t.EmptyTrash(ui.Confirm, ui.Cancel, ui.Render)
+	}},
+	{Name: "purgeTrash", Description: "remove the objects trashed before the days", Mutates: true, Run: func(t *Tree, ui UI) (interface{}, error) {
+		return nil, t.PurgeTrash(textOf(ui, "purge trashed before days: "), ui.Confirm, ui.Cancel, ui.Render)
+	}},
+	{Name: "enforceTrashQuota", Description: "remove the oldest objects in the trash over the quota", Mutates: true, Run: func(t *Tree, ui UI) (interface{}, error) {
+		return nil, t.EnforceTrashQuota(ui.Confirm, ui.Cancel, ui.Render)
+	}},
+	{Name: "copy", Description: "copy the objects", UsesSelection: true, Run: func(t *Tree, ui UI) (interface{}, error) {
+		return nil, t.Copy(ui.Cursor)
+	}},
+	{Name: "paste", Description: "paste the copied objects", Mutates: true, Run: func(t *Tree, ui UI) (interface{}, error) {
+		return nil, t.Paste(ui.Cursor, chooseOf(ui, "paste: "), operatorTextOf(ui, "paste '%s' as: "), ui.SetCursor, ui.Render)
+	}},
+	{Name: "yank", Description: "yank the path to the clipboard", Run: func(t *Tree, ui UI) (interface{}, error) {
+		return nil, t.Yank(ui.Cursor, ui.SetClipboard)
+	}},
+	{Name: "compress", Description: "compress the objects into an archive", Mutates: true, UsesSelection: true, Run: func(t *Tree, ui UI) (interface{}, error) {
+		return nil, t.Compress(ui.Cursor, operatorsTextOf(ui, "compress %d objects to: "), ui.Cancel, ui.SetCursor, ui.Render)
+	}},
+	{Name: "extract", Description: "extract the archive", Mutates: true, Run: func(t *Tree, ui UI) (interface{}, error) {
+		return nil, t.Extract(ui.Cursor, operatorTextOf(ui, "extract '%s' to: "), chooseOf(ui, "extract: "), ui.Cancel, ui.SetCursor, ui.Render)
+	}},
+	{Name: "openExternally", Description: "open the object with the default application", Run: func(t *Tree, ui UI) (interface{}, error) {
+		return nil, t.OpenExternally(ui.Cursor, ui.Render)
+	}},
+	{Name: "openDirExternally", Description: "open the directory of the object with the default application", UsesSelection: true, Run: func(t *Tree, ui UI) (interface{}, error) {
+		return nil, t.OpenDirExternally(ui.Cursor, ui.Render)
+	}},
+	{Name: "addBookmark", Description: "bookmark the object", Run: func(t *Tree, ui UI) (interface{}, error) {
+		return nil, t.AddBookmark(ui.Cursor, operatorTextOf(ui, "bookmark '%s' as: "))
+	}},
+	{Name: "jumpBookmark", Description: "set the bookmarked directory as the root", Run: func(t *Tree, ui UI) (interface{}, error) {
+		return nil, t.JumpBookmark(ui.Cursor, chooseOf(ui, "bookmark: "), ui.Cancel, ui.Render)
+	}},
+	{Name: "deleteBookmark", Description: "delete the bookmark", Run: func(t *Tree, ui UI) (interface{}, error) {
+		return nil, t.DeleteBookmark(chooseOf(ui, "delete bookmark: "), ui.Cancel)
+	}},
+	{Name: "jump", Description: "set the frequently visited directory as the root", Run: func(t *Tree, ui UI) (interface{}, error) {
+		return nil, t.Jump(ui.Cursor, textOf(ui, "jump: "), ui.Cancel, ui.Render)
+	}},
+	{Name: "compare", Description: "set the view comparing the two directories as the root", Run: func(t *Tree, ui UI) (interface{}, error) {
+		method, err := compareMethodOf(ui, "compare by: ")
+		if err != nil {
+			return nil, err
+		}
+		return nil, t.Compare(textOf(ui, "left: "), textOf(ui, "right: "), method, ui.Render)
+	}},
+	{Name: "copyToLeft", Description: "copy the differing objects to the left directory", Mutates: true, UsesSelection: true, Run: func(t *Tree, ui UI) (interface{}, error) {
+		return nil, t.CopyToLeft(ui.Cursor, chooseOf(ui, "copy to left: "), operatorTextOf(ui, "copy '%s' as: "), ui.SetCursor, ui.Render)
+	}},
+	{Name: "copyToRight", Description: "copy the differing objects to the right directory", Mutates: true, UsesSelection: true, Run: func(t *Tree, ui UI) (interface{}, error) {
+		return nil, t.CopyToRight(ui.Cursor, chooseOf(ui, "copy to right: "), operatorTextOf(ui, "copy '%s' as: "), ui.SetCursor, ui.Render)
+	}},
+	{Name: "preview", Description: "return the preview of the object", Run: func(t *Tree, ui UI) (interface{}, error) {
+		var preview Preview
+		err := t.Preview(ui.Cursor, func(p Preview) error {
+			preview = p
+			return nil
+		})
+		return preview, err
+	}},
+}
@@ -0,0 +1,126 @@
+package tree_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	tree "github.com/minodisk/go-tree"
+)
+
+// fakeUI answers the callbacks with the fields.
+type fakeUI struct {
+	cursor  int
+	inputs  []string
+	confirm bool
+	lines   [][]byte
+}
+
+func (u *fakeUI) Cursor() (int, error)                   { return u.cursor, nil }
+func (u *fakeUI) SetCursor(c int) error                  { u.cursor = c; return nil }
+func (u *fakeUI) Render(lines [][]byte) error            { u.lines = lines; return nil }
+func (u *fakeUI) Confirm(...tree.Operator) (bool, error) { return u.confirm, nil }
+func (u *fakeUI) Input(prompt, value string) (string, error) {
+	if len(u.inputs) == 0 {
+		return "", errors.New("canceled")
+	}
+	return u.inputs[0], nil
+}
+func (u *fakeUI) Inputs(prompt string, values []string) ([]string, error) { return u.inputs, nil }
+func (u *fakeUI) Choose(prompt string, cs []string) (string, error)       { return cs[0], nil }
+func (u *fakeUI) Cancel() error                                           { return nil }
+func (u *fakeUI) OpenFile(*tree.File) error                               { return nil }
+func (u *fakeUI) SetClipboard(string) error                               { return nil }
+
+func TestDispatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-tree-command")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	root := filepath.Join(dir, "root")
+	if err := os.MkdirAll(root, 0775); err != nil {
+		t.Fatal(err)
+	}
+	tr, err := tree.New(root, &tree.Context{Config: &tree.Config{
+		TrashDirname:   filepath.Join(dir, "trash"),
+		VisitsFilename: filepath.Join(dir, "visits.json"),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ui := &fakeUI{inputs: []string{"a.txt", "b.txt"}}
+	if _, err := tr.Dispatch("createFile", ui); err != nil {
+		t.Fatal(err)
+	}
+	if a, e := linesToString(ui.lines), "root/\n| a.txt\n| b.txt"; a != e {
+		t.Errorf("createFile should create the files\nexpected:\n%s\nactual:\n%s", e, a)
+	}
+	if c, ok := tr.Commands().Lookup("createFile"); !ok || !c.Mutates || c.Description == "" {
+		t.Errorf("createFile should be registered as the mutating command")
+	}
+
+	for _, name := range []string{"clearSelection", "hiddenSelecteds"} {
+		if _, err := tr.Dispatch(name, ui); err != tree.ErrNoSelection {
+			t.Errorf("%s needing the selection should fail without it, but %v", name, err)
+		}
+	}
+	for _, c := range []struct {
+		name                   string
+		mutates, usesSelection bool
+	}{
+		{"selectRange", false, false},
+		{"trashView", false, false},
+		{"purgeTrash", true, false},
+		{"enforceTrashQuota", true, false},
+		{"deleteBookmark", false, false},
+		{"openDirExternally", false, true},
+		{"compare", false, false},
+		{"copyToLeft", true, true},
+		{"copyToRight", true, true},
+	} {
+		if a, ok := tr.Commands().Lookup(c.name); !ok || a.Mutates != c.mutates || a.UsesSelection != c.usesSelection || a.NeedsSelection {
+			t.Errorf("%s should be registered with the metadata, but %+v", c.name, a)
+		}
+	}
+	ui.cursor, ui.inputs = 2, []string{"1"}
+	if n, err := tr.Dispatch("selectRange", ui); err != nil || n != 2 {
+		t.Errorf("selectRange should select the objects between the cursor and the row, but %v, %v", n, err)
+	}
+	if _, err := tr.Dispatch("clearSelection", ui); err != nil {
+		t.Fatal(err)
+	}
+	ui.cursor = 1
+	if _, err := tr.Dispatch("select", ui); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := tr.Dispatch("clearSelection", ui); err != nil || n != 1 {
+		t.Errorf("clearSelection should return the number of the unselected objects, but %v, %v", n, err)
+	}
+
+	count := tree.Command{Name: "count", Description: "count the rows", Run: func(t *tree.Tree, ui tree.UI) (interface{}, error) {
+		return len(t.Lines()), nil
+	}}
+	if err := tr.Commands().Register(count); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Commands().Register(count); err == nil {
+		t.Errorf("Register() should fail with the registered name")
+	}
+	if n, err := tr.Dispatch("count", ui); err != nil || n != 3 {
+		t.Errorf("the registered command should be dispatched, but returns %v, %v", n, err)
+	}
+	found := false
+	for _, name := range tr.Commands().Names() {
+		found = found || name == "count"
+	}
+	if !found {
+		t.Errorf("Names() should contain the registered command")
+	}
+	if _, err := tr.Dispatch("unknown", ui); err == nil {
+		t.Errorf("Dispatch() should fail with the unknown command")
+	}
+}
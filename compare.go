@@ -0,0 +1,306 @@
+package tree
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Difference is the state of an entry compared between two directories.
+type Difference int
+
+const (
+	// OnlyLeft means the entry exists only in the left directory.
+	OnlyLeft Difference = iota
+	// OnlyRight means the entry exists only in the right directory.
+	OnlyRight
+	// Identical means the entries are the same in both directories.
+	Identical
+	// Differing means the entries differ between the directories.
+	Differing
+)
+
+// Mark returns the mark rendered before the name of the entry.
+func (d Difference) Mark() string {
+	switch d {
+	case OnlyLeft:
+		return "<"
+	case OnlyRight:
+		return ">"
+	case Identical:
+		return "="
+	default:
+		return "!"
+	}
+}
+
+// CompareMethod decides how files in both directories are compared.
+type CompareMethod int
+
+const (
+	// CompareModTime treats files with the same size and modification time as identical.
+	CompareModTime CompareMethod = iota
+	// CompareContent treats files with the same hash of the content as identical.
+	CompareContent
+)
+
+// A Comparison is the result of comparing two local directories.
+// Entries are keyed by the slash separated path relative to the directories.
+type Comparison struct {
+	Left   string
+	Right  string
+	Method CompareMethod
+
+	lefts    map[string]os.FileInfo
+	rights   map[string]os.FileInfo
+	children map[string][]string
+	diffs    map[string]Difference
+
+	// hashes are the hashes of the files keyed by the path,
+	// kept across comparing again while the files are unchanged.
+	hashes map[string]fileHash
+}
+
+// A fileHash is the hash of a file with the size and the modification time
+// at hashing.
+type fileHash struct {
+	size    int64
+	modTime time.Time
+	sum     []byte
+}
+
+// Compare compares the directories at left and right.
+func Compare(left, right string, method CompareMethod) (*Comparison, error) {
+	c := &Comparison{Left: left, Right: right, Method: method}
+	if err := c.compare(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Comparison) compare() error {
+	var err error
+	if c.lefts, err = walkRel(c.Left); err != nil {
+		return err
+	}
+	if c.rights, err = walkRel(c.Right); err != nil {
+		return err
+	}
+	c.children = map[string][]string{}
+	for rel := range c.lefts {
+		if rel != "" {
+			c.children[relDir(rel)] = append(c.children[relDir(rel)], rel)
+		}
+	}
+	for rel := range c.rights {
+		if _, ok := c.lefts[rel]; !ok && rel != "" {
+			c.children[relDir(rel)] = append(c.children[relDir(rel)], rel)
+		}
+	}
+	for _, rels := range c.children {
+		sort.Strings(rels)
+	}
+	c.diffs = map[string]Difference{}
+	_, err = c.diff("")
+	return err
+}
+
+// Difference returns the state of the entry at the relative path.
+func (c *Comparison) Difference(rel string) Difference {
+	return c.diffs[rel]
+}
+
+// diff computes the state of the entry at rel and the ones under it.
+func (c *Comparison) diff(rel string) (Difference, error) {
+	l, inLeft := c.lefts[rel]
+	r, inRight := c.rights[rel]
+	var d Difference
+	switch {
+	case !inRight:
+		d = OnlyLeft
+	case !inLeft:
+		d = OnlyRight
+	case l.IsDir() != r.IsDir():
+		d = Differing
+	case l.IsDir():
+		d = Identical
+	default:
+		same, err := c.sameFile(rel, l, r)
+		if err != nil {
+			return d, err
+		}
+		if same {
+			d = Identical
+		} else {
+			d = Differing
+		}
+	}
+	for _, child := range c.children[rel] {
+		cd, err := c.diff(child)
+		if err != nil {
+			return d, err
+		}
+		if d == Identical && cd != Identical {
+			d = Differing
+		}
+	}
+	c.diffs[rel] = d
+	return d, nil
+}
+
+func (c *Comparison) sameFile(rel string, l, r os.FileInfo) (bool, error) {
+	if l.Size() != r.Size() {
+		return false, nil
+	}
+	if c.Method == CompareModTime {
+		return l.ModTime().Equal(r.ModTime()), nil
+	}
+	lh, err := c.hash(filepath.Join(c.Left, filepath.FromSlash(rel)), l)
+	if err != nil {
+		return false, err
+	}
+	rh, err := c.hash(filepath.Join(c.Right, filepath.FromSlash(rel)), r)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(lh, rh), nil
+}
+
+// hash returns the hash of the file at name,
+// which is computed again only when the size or the modification time changes.
+func (c *Comparison) hash(name string, info os.FileInfo) ([]byte, error) {
+	if h, ok := c.hashes[name]; ok && h.size == info.Size() && h.modTime.Equal(info.ModTime()) {
+		return h.sum, nil
+	}
+	sum, err := hashFile(name)
+	if err != nil {
+		return nil, err
+	}
+	if c.hashes == nil {
+		c.hashes = map[string]fileHash{}
+	}
+	c.hashes[name] = fileHash{size: info.Size(), modTime: info.ModTime(), sum: sum}
+	return sum, nil
+}
+
+// bothDirs returns that the entry at rel is a directory on both sides.
+func (c *Comparison) bothDirs(rel string) bool {
+	l, inLeft := c.lefts[rel]
+	r, inRight := c.rights[rel]
+	return inLeft && inRight && l.IsDir() && r.IsDir()
+}
+
+// Rel returns the relative path of o in the compared directories.
+func (c *Comparison) Rel(o Operator) (string, error) {
+	for _, root := range []string{c.Left, c.Right} {
+		rel, err := filepath.Rel(root, o.Path())
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if rel == "." {
+			return "", nil
+		}
+		return filepath.ToSlash(rel), nil
+	}
+	return "", fmt.Errorf("'%s' isn't in the compared directories", o.Path())
+}
+
+// NewCompareView creates the virtual directory merging the compared directories.
+// Scanning the view compares the directories again,
+// hashing only the files changed since the last comparison.
+func NewCompareView(c *Comparison, context *Context) *Dir {
+	name := filepath.Base(c.Left) + " <> " + filepath.Base(c.Right)
+	root := newVirtualDir(name, filepath.Dir(c.Left), context, nil)
+	root.comparison = c
+	root.list = func() (Operators, error) {
+		if err := c.compare(); err != nil {
+			return nil, err
+		}
+		return c.entries("", context)
+	}
+	return root
+}
+
+// entries creates the objects shown under the entry at rel.
+// The directories are virtual to merge both sides,
+// and the files are the ones in the left directory if they exist there.
+func (c *Comparison) entries(rel string, context *Context) (Operators, error) {
+	os := Operators{}
+	for _, child := range c.children[rel] {
+		child := child
+		dirname, info := c.Left, c.lefts[child]
+		if info == nil {
+			dirname, info = c.Right, c.rights[child]
+		}
+		p := filepath.Join(dirname, filepath.FromSlash(child))
+		if !info.IsDir() {
+			os = append(os, &File{FileInfo: info, context: context, dirname: filepath.Dir(p), fs: LocalFS})
+			continue
+		}
+		d := newVirtualDir(info.Name(), filepath.Dir(p), context, func() (Operators, error) {
+			return c.entries(child, context)
+		})
+		d.comparison = c
+		os = append(os, d)
+	}
+	return os, nil
+}
+
+// walkRel returns the infos of the objects under dirname
+// keyed by the slash separated relative path.
+func walkRel(dirname string) (map[string]os.FileInfo, error) {
+	infos := map[string]os.FileInfo{}
+	err := filepath.Walk(dirname, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dirname, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			rel = ""
+		}
+		infos[filepath.ToSlash(rel)] = info
+		return nil
+	})
+	return infos, err
+}
+
+func relDir(rel string) string {
+	i := strings.LastIndex(rel, "/")
+	if i == -1 {
+		return ""
+	}
+	return rel[:i]
+}
+
+func hashFile(name string) ([]byte, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// comparisonOf returns the comparison showing o.
+func comparisonOf(o Operator) (*Comparison, bool) {
+	if d, ok := o.(*Dir); ok && d.comparison != nil {
+		return d.comparison, true
+	}
+	if p := o.Parent(); p != nil && p.comparison != nil {
+		return p.comparison, true
+	}
+	return nil, false
+}
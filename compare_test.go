@@ -0,0 +1,134 @@
+package tree_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	tree "github.com/minodisk/go-tree"
+)
+
+func TestCompare(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-tree-compare")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, content := range map[string]string{
+		"left/same.txt":      "same",
+		"left/diff.txt":      "left",
+		"left/left.txt":      "left",
+		"left/sub/a.txt":     "a",
+		"right/same.txt":     "same",
+		"right/diff.txt":     "right",
+		"right/right.txt":    "right",
+		"right/sub/a.txt":    "a",
+		"right/sub/more.txt": "more",
+	} {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0775); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0664); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tr, clean := newTree(t, dir)
+	defer clean()
+
+	left, right := filepath.Join(dir, "left"), filepath.Join(dir, "right")
+	if err := tr.Compare(textOf(left), textOf(right), tree.CompareContent, noRender); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Toggle(cursorAt(1), noRender); err != nil {
+		t.Fatal(err)
+	}
+	a := linesToString(tr.Lines())
+	e := `left <> right/
+- ! sub/
+ | = a.txt
+ | > more.txt
+| ! diff.txt
+| < left.txt
+| > right.txt
+| = same.txt`
+	if a != e {
+		t.Fatalf("Compare() should render the merged tree with the differences\nexpected:\n%s\nactual:\n%s", e, a)
+	}
+
+	overwrite := func([]string) (string, error) { return "overwrite", nil }
+	if err := tr.CopyToRight(cursorAt(4), overwrite, nil, func(int) error { return nil }, noRender); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := ioutil.ReadFile(filepath.Join(right, "diff.txt")); err != nil || string(b) != "left" {
+		t.Errorf("CopyToRight() should overwrite the differing file, but the content is '%s': %v", b, err)
+	}
+	if err := tr.CopyToLeft(cursorAt(1), overwrite, nil, func(int) error { return nil }, noRender); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(left, "sub", "more.txt")); err != nil {
+		t.Errorf("CopyToLeft() should copy the missing files in the directory: %v", err)
+	}
+	a = linesToString(tr.Lines())
+	e = `left <> right/
+- = sub/
+ | = a.txt
+ | = more.txt
+| = diff.txt
+| < left.txt
+| > right.txt
+| = same.txt`
+	if a != e {
+		t.Errorf("the view should be compared again after copying\nexpected:\n%s\nactual:\n%s", e, a)
+	}
+}
+
+func TestCompareHashCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-tree-compare")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	left, right := filepath.Join(dir, "left"), filepath.Join(dir, "right")
+	for _, d := range []string{left, right} {
+		if err := os.MkdirAll(d, 0775); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(d, "a.txt"), []byte("aaaa"), 0664); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tr, clean := newTree(t, dir)
+	defer clean()
+	if err := tr.Compare(textOf(left), textOf(right), tree.CompareContent, noRender); err != nil {
+		t.Fatal(err)
+	}
+
+	p := filepath.Join(right, "a.txt")
+	info, err := os.Stat(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(p, []byte("bbbb"), 0664); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		modTime time.Time
+		e       string
+	}{
+		{info.ModTime(), "left <> right/\n| = a.txt"},
+		{info.ModTime().Add(time.Second), "left <> right/\n| ! a.txt"},
+	} {
+		if err := os.Chtimes(p, c.modTime, c.modTime); err != nil {
+			t.Fatal(err)
+		}
+		if err := tr.ScanAndRender(noRender); err != nil {
+			t.Fatal(err)
+		}
+		if a := linesToString(tr.Lines()); a != c.e {
+			t.Errorf("scanning should hash only the changed files\nexpected:\n%s\nactual:\n%s", c.e, a)
+		}
+	}
+}
@@ -1,9 +1,15 @@
 package tree
 
 import (
+	"fmt"
+	"net/url"
+	"os"
 	"os/user"
 	"path/filepath"
 	"regexp"
+	"sync"
+
+	"github.com/pkg/sftp"
 )
 
 var (
@@ -15,6 +21,16 @@ var (
 		PrefixSelected:  "*",
 		PostfixDir:      "/",
 		RegexpProject:   `^(?:\.git)$`,
+		PreviewLines:    40,
+		PreviewBytes:    256,
+		PreviewFiles:    10000,
+		IconDirOpened:   "\uf07c",
+		IconDirClosed:   "\uf07b",
+		IconFile:        "\uf15b",
+		IconSymlink:     "\uf481",
+		LSColors:        LSColorsDefault,
+		RenderStyle:     RenderIndent,
+		SortBy:          SortName,
 	}
 )
 
@@ -25,6 +41,15 @@ func init() {
 			return err
 		}
 		ConfigDefault.TrashDirname = filepath.Join(u.HomeDir, ".finder-trash")
+		dataHome := os.Getenv("XDG_DATA_HOME")
+		if dataHome == "" {
+			dataHome = filepath.Join(u.HomeDir, ".local", "share")
+		}
+		ConfigDefault.BookmarksFilename = filepath.Join(dataHome, "finder", "bookmarks.json")
+		ConfigDefault.VisitsFilename = filepath.Join(dataHome, "finder", "visits.json")
+		if c := os.Getenv("LS_COLORS"); c != "" {
+			ConfigDefault.LSColors = c
+		}
 		return nil
 	}(); err != nil {
 		panic(err)
@@ -34,6 +59,38 @@ func init() {
 type Context struct {
 	Config   *Config
 	Registry Operators
+
+	// DialSFTP connects to the host in the URL.
+	// When it is nil, connects with SSH authenticated by ssh-agent
+	// and the password in the URL.
+	DialSFTP func(*url.URL) (*sftp.Client, error)
+
+	// Filter decides the objects shown in the tree.
+	// The objects for which it returns false are hidden.
+	Filter func(Operator) bool
+
+	// Commands is the commands dispatched by name.
+	// When it is nil, the built-in commands are set by Init.
+	Commands *Commands
+
+	mu          sync.Mutex
+	sftpClients map[string]*sftp.Client
+
+	// selection is the set of the selected objects keyed by the position,
+	// so that the selection survives closing directories and changing roots.
+	selection map[position]Operator
+
+	// before and after are the hooks of the events keyed by the kind.
+	before map[string][]Hook
+	after  map[string][]Hook
+
+	// visits are read from VisitsFilename once, and unsaved is the number
+	// of the changes to them not written yet. visitsErr is the first error
+	// of recording a visit, reported by FlushVisits.
+	visits       Visits
+	visitsLoaded bool
+	unsaved      int
+	visitsErr    error
 }
 
 func (c *Context) Init() error {
@@ -41,20 +98,43 @@ func (c *Context) Init() error {
 		c.Config = &Config{}
 	}
 	c.Config.FillWithDefault()
+	if c.Commands == nil {
+		c.Commands = NewCommands()
+	}
 	return c.Config.Compile()
 }
 
 type Config struct {
-	Indent          string
-	PrefixDirOpened string
-	PrefixDirClosed string
-	PrefixFile      string
-	PrefixSelected  string
-	PostfixDir      string
-	TrashDirname    string
-	RegexpProject   string
+	Indent            string
+	PrefixDirOpened   string
+	PrefixDirClosed   string
+	PrefixFile        string
+	PrefixSelected    string
+	PostfixDir        string
+	TrashDirname      string
+	TrashQuota        int64
+	TrashGroupByDate  bool
+	BookmarksFilename string
+	VisitsFilename    string
+	RegexpProject     string
+	PreviewLines      int
+	PreviewBytes      int
+	PreviewFiles      int
+	Icons             bool
+	IconDirOpened     string
+	IconDirClosed     string
+	IconFile          string
+	IconSymlink       string
+	IconsByName       map[string]string
+	IconsByExtension  map[string]string
+	LSColors          string
+	RenderStyle       string
+	SortBy            string
+	SortReverse       bool
+	ArchivesAsFiles   bool
 
 	rProject *regexp.Regexp
+	lsColors *LSColors
 }
 
 func (c *Config) FillWithDefault() {
@@ -79,13 +159,66 @@ func (c *Config) FillWithDefault() {
 	if c.TrashDirname == "" {
 		c.TrashDirname = ConfigDefault.TrashDirname
 	}
+	if c.BookmarksFilename == "" {
+		c.BookmarksFilename = ConfigDefault.BookmarksFilename
+	}
+	if c.VisitsFilename == "" {
+		c.VisitsFilename = ConfigDefault.VisitsFilename
+	}
 	if c.RegexpProject == "" {
 		c.RegexpProject = ConfigDefault.RegexpProject
 	}
+	if c.PreviewLines == 0 {
+		c.PreviewLines = ConfigDefault.PreviewLines
+	}
+	if c.PreviewBytes == 0 {
+		c.PreviewBytes = ConfigDefault.PreviewBytes
+	}
+	if c.PreviewFiles == 0 {
+		c.PreviewFiles = ConfigDefault.PreviewFiles
+	}
+	if c.IconDirOpened == "" {
+		c.IconDirOpened = ConfigDefault.IconDirOpened
+	}
+	if c.IconDirClosed == "" {
+		c.IconDirClosed = ConfigDefault.IconDirClosed
+	}
+	if c.IconFile == "" {
+		c.IconFile = ConfigDefault.IconFile
+	}
+	if c.IconSymlink == "" {
+		c.IconSymlink = ConfigDefault.IconSymlink
+	}
+	c.IconsByName = mergeIcons(IconsByNameDefault, c.IconsByName)
+	c.IconsByExtension = mergeIcons(IconsByExtensionDefault, c.IconsByExtension)
+	if c.LSColors == "" {
+		c.LSColors = ConfigDefault.LSColors
+	}
+	if c.RenderStyle == "" {
+		c.RenderStyle = ConfigDefault.RenderStyle
+	}
+	if c.SortBy == "" {
+		c.SortBy = ConfigDefault.SortBy
+	}
+}
+
+func checkSortBy(sortBy string) error {
+	switch sortBy {
+	case SortName, SortSize, SortModTime:
+		return nil
+	}
+	return fmt.Errorf("unknown sort order '%s'", sortBy)
 }
 
 func (c *Config) Compile() error {
+	if _, ok := guides[c.RenderStyle]; !ok && c.RenderStyle != RenderIndent {
+		return fmt.Errorf("unknown render style '%s'", c.RenderStyle)
+	}
+	if err := checkSortBy(c.SortBy); err != nil {
+		return err
+	}
 	var err error
 	c.rProject, err = regexp.Compile(c.RegexpProject)
+	c.lsColors = ParseLSColors(c.LSColors)
 	return err
 }
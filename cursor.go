@@ -0,0 +1,70 @@
+package tree
+
+// A position is the place of an object the cursor follows
+// while the rows shift.
+type position struct {
+	fs   FileSystem
+	path string
+}
+
+// A tracker finds the row the cursor should land on after a command
+// changes the objects in the tree.
+type tracker struct {
+	tree *Tree
+
+	// targets are the objects made by the command, like the renamed
+	// or the pasted one. The first one shown in the tree is preferred.
+	targets []position
+
+	// around are the objects at the rows from the cursor downward,
+	// followed by the ones upward, to find the nearest surviving neighbour.
+	around []position
+}
+
+// track records the objects around the cursor before the command.
+func (t *Tree) track(cursor CursorFunc) *tracker {
+	tr := &tracker{tree: t}
+	c, err := cursor()
+	if err != nil {
+		return tr
+	}
+	os := t.All()
+	if c < 0 || c >= len(os) {
+		return tr
+	}
+	for i := c; i < len(os); i++ {
+		tr.around = append(tr.around, position{FileSystemOf(os[i]), os[i].Path()})
+	}
+	for i := c - 1; i >= 0; i-- {
+		tr.around = append(tr.around, position{FileSystemOf(os[i]), os[i].Path()})
+	}
+	return tr
+}
+
+// target adds the object at the path as a destination of the cursor.
+func (tr *tracker) target(fs FileSystem, path string) {
+	tr.targets = append(tr.targets, position{fs, path})
+}
+
+// scanAndRender scans and renders the tree,
+// then sets the cursor on the row of the first surviving object.
+func (tr *tracker) scanAndRender(setCursor SetCursorFunc, render RenderFunc) error {
+	if err := tr.tree.ScanAndRender(render); err != nil {
+		return err
+	}
+	rows := map[position]int{}
+	for i, o := range tr.tree.All() {
+		p := position{FileSystemOf(o), o.Path()}
+		if _, ok := rows[p]; !ok {
+			rows[p] = i
+		}
+	}
+	for _, ps := range [][]position{tr.targets, tr.around} {
+		for _, p := range ps {
+			if i, ok := rows[p]; ok {
+				return setCursor(i)
+			}
+		}
+	}
+	return nil
+}
@@ -3,11 +3,10 @@ package tree
 import (
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"os"
 	"path/filepath"
-	"sort"
 	"strings"
+	"time"
 )
 
 type Dir struct {
@@ -16,19 +15,32 @@ type Dir struct {
 	os.FileInfo
 	dirname string
 	parent  *Dir
+	fs      FileSystem
 
-	selected bool
 	opened   bool
 	children Operators
+
+	// list returns the children of a virtual directory,
+	// which doesn't exist on file system.
+	list func() (Operators, error)
+
+	// comparison is the comparison shown in the children
+	// of a directory in the view of comparison.
+	comparison *Comparison
 }
 
 func NewDir(path string, context *Context) (*Dir, error) {
+	return newDir(path, context, LocalFS)
+}
+
+func newDir(path string, context *Context, fs FileSystem) (*Dir, error) {
 	var err error
 	d := &Dir{
 		context: context,
 		dirname: filepath.Dir(path),
+		fs:      fs,
 	}
-	d.FileInfo, err = os.Stat(path)
+	d.FileInfo, err = fs.Stat(path)
 	if err != nil {
 		return nil, err
 	}
@@ -38,6 +50,29 @@ func NewDir(path string, context *Context) (*Dir, error) {
 	return d, err
 }
 
+// newVirtualDir creates a directory whose children are returned by list.
+func newVirtualDir(name, dirname string, context *Context, list func() (Operators, error)) *Dir {
+	return &Dir{
+		context:  context,
+		FileInfo: virtualInfo{name: name},
+		dirname:  dirname,
+		list:     list,
+	}
+}
+
+// virtualInfo describes a virtual directory.
+type virtualInfo struct {
+	name    string
+	modTime time.Time
+}
+
+func (i virtualInfo) Name() string       { return i.name }
+func (i virtualInfo) Size() int64        { return 0 }
+func (i virtualInfo) Mode() os.FileMode  { return os.ModeDir | 0555 }
+func (i virtualInfo) ModTime() time.Time { return i.modTime }
+func (i virtualInfo) IsDir() bool        { return true }
+func (i virtualInfo) Sys() interface{}   { return nil }
+
 func (d *Dir) Context() *Context {
 	return d.context
 }
@@ -58,20 +93,31 @@ func (d *Dir) Path() string {
 	return filepath.Join(d.dirname, d.Name())
 }
 
+func (d *Dir) FileSystem() FileSystem {
+	if d.fs == nil {
+		return LocalFS
+	}
+	return d.fs
+}
+
 func (d *Dir) Selected() bool {
-	return d.selected
+	return d.context.isSelected(d)
 }
 
 func (d *Dir) Select() {
-	d.selected = true
+	d.context.selectOperator(d)
 }
 
 func (d *Dir) Unselect() {
-	d.selected = false
+	d.context.unselectOperator(d)
 }
 
 func (d *Dir) ToggleSelected() {
-	d.selected = !d.selected
+	if d.Selected() {
+		d.Unselect()
+	} else {
+		d.Select()
+	}
 }
 
 // Non interface methods
@@ -84,44 +130,82 @@ func (d *Dir) Scan() error {
 	olds := d.children
 
 	d.children = Operators{}
-	dirname := d.Path()
-	infos, err := ioutil.ReadDir(dirname)
+	news, err := d.read()
 	if err != nil {
 		return err
 	}
-	for _, info := range infos {
-		var o Operator
-		if info.IsDir() {
-			newDir := &Dir{FileInfo: info, context: d.context, dirname: dirname}
-			oldDir := olds.FindDir(newDir)
+	filter := d.context.Filter
+	for _, o := range news {
+		if filter != nil && !filter(o) {
+			continue
+		}
+		switch n := o.(type) {
+		case *Dir:
+			oldDir := olds.FindDir(n)
 			if oldDir != nil {
+				oldDir.list = n.list
 				oldDir.Scan()
 				o = oldDir
-			} else {
-				o = newDir
 			}
-		} else {
-			newFile := &File{FileInfo: info, context: d.context, dirname: dirname}
-			oldFile := olds.FindFile(newFile)
+		case *File:
+			oldFile := olds.FindFile(n)
 			if oldFile != nil {
 				o = oldFile
-			} else {
-				o = newFile
+			}
+		default:
+			old := olds.Find(n)
+			if old != nil {
+				o = old
 			}
 		}
 		d.AppendChild(o)
 	}
-	sort.Sort(d.children)
+	d.context.Config.sort(d.children)
 	return nil
 }
 
+func (d *Dir) read() (Operators, error) {
+	if d.list != nil {
+		return d.list()
+	}
+	fs := d.FileSystem()
+	dirname := d.Path()
+	infos, err := fs.ReadDir(dirname)
+	if err != nil {
+		return nil, err
+	}
+	os := Operators{}
+	for _, info := range infos {
+		if info.IsDir() {
+			os = append(os, &Dir{FileInfo: info, context: d.context, dirname: dirname, fs: fs})
+		} else if fs == LocalFS && d.context.Config.browsesArchives() && ArchiveFormat(info.Name()) != "" {
+			os = append(os, newArchiveDir(info, dirname, d.context))
+		} else {
+			os = append(os, &File{FileInfo: info, context: d.context, dirname: dirname, fs: fs})
+		}
+	}
+	return os, nil
+}
+
+// Virtual returns that d doesn't exist on file system.
+func (d *Dir) Virtual() bool {
+	_, ok := d.FileInfo.(virtualInfo)
+	return ok
+}
+
+// ReadOnly returns that objects can't be created under d,
+// such as virtual directories and archives.
+func (d *Dir) ReadOnly() bool {
+	return d.list != nil
+}
+
 func (d *Dir) OpenRec() error {
 	if err := d.Open(); err != nil {
 		return err
 	}
 	for _, o := range d.children {
 		c, ok := o.(*Dir)
-		if !ok {
+		if !ok || IsArchive(c) {
 			continue
 		}
 		if err := c.OpenRec(); err != nil {
@@ -131,6 +215,34 @@ func (d *Dir) OpenRec() error {
 	return nil
 }
 
+// OpenDepth opens d and the descendants down to depth below d.
+// When depth is zero or less, opens all descendants like OpenRec.
+func (d *Dir) OpenDepth(depth int) error {
+	if depth <= 0 {
+		return d.OpenRec()
+	}
+	return d.openDepth(depth)
+}
+
+func (d *Dir) openDepth(depth int) error {
+	if err := d.Open(); err != nil {
+		return err
+	}
+	if depth == 1 {
+		return nil
+	}
+	for _, o := range d.children {
+		c, ok := o.(*Dir)
+		if !ok || IsArchive(c) {
+			continue
+		}
+		if err := c.openDepth(depth - 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (d *Dir) Opened() bool {
 	return d.opened
 }
@@ -165,6 +277,101 @@ func (d *Dir) NumChildren() int {
 	return len(d.children)
 }
 
+// OpenedPaths returns the paths of the opened directories under d including d.
+func (d *Dir) OpenedPaths() []string {
+	ps := []string{}
+	if !d.opened {
+		return ps
+	}
+	ps = append(ps, d.Path())
+	for _, o := range d.children {
+		if c, ok := o.(*Dir); ok {
+			ps = append(ps, c.OpenedPaths()...)
+		}
+	}
+	return ps
+}
+
+// OpenPaths opens d and the directories at the paths under d,
+// and closes the other directories.
+// The paths which no longer exist are skipped.
+func (d *Dir) OpenPaths(paths []string) error {
+	opened := map[string]bool{}
+	for _, p := range paths {
+		opened[p] = true
+	}
+	d.Close()
+	return d.openPaths(opened)
+}
+
+func (d *Dir) openPaths(opened map[string]bool) error {
+	if err := d.Open(); err != nil {
+		return err
+	}
+	for _, o := range d.children {
+		c, ok := o.(*Dir)
+		if !ok || !opened[c.Path()] {
+			continue
+		}
+		if err := c.openPaths(opened); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reveal opens d and the directories under d down to the object at the path,
+// and returns the object.
+func (d *Dir) reveal(p string) (Operator, error) {
+	dp, err := filepath.Abs(d.Path())
+	if err != nil {
+		return nil, err
+	}
+	rel, err := filepath.Rel(dp, p)
+	if err != nil {
+		return nil, err
+	}
+	var o Operator = d
+	if rel == "." {
+		return o, nil
+	}
+	for _, name := range strings.Split(rel, string(filepath.Separator)) {
+		dir, ok := o.(*Dir)
+		if !ok {
+			return nil, &os.PathError{Op: "reveal", Path: p, Err: os.ErrNotExist}
+		}
+		if !dir.Opened() {
+			if err := dir.Open(); err != nil {
+				return nil, err
+			}
+		}
+		o = nil
+		for _, c := range dir.children {
+			if c.Name() == name {
+				o = c
+				break
+			}
+		}
+		if o == nil {
+			return nil, &os.PathError{Op: "reveal", Path: p, Err: os.ErrNotExist}
+		}
+	}
+	return o, nil
+}
+
+// NumLines returns the number of rows rendered by Lines.
+func (d *Dir) NumLines() int {
+	n := 1
+	for _, o := range d.children {
+		if c, ok := o.(*Dir); ok {
+			n += c.NumLines()
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
 func (d *Dir) AppendChild(o Operator) {
 	d.children = append(d.children, o)
 	o.SetParent(d)
@@ -202,13 +409,13 @@ func (d *Dir) objectsAt(r Range, ctx *ContextOperators) bool {
 			return false
 		}
 
-		if o, ok := o.(*Dir); ok {
-			if !o.objectsAt(r, ctx) {
+		if c, ok := o.(*Dir); ok {
+			if !c.objectsAt(r, ctx) {
 				return false
 			}
 		} else {
 			if r.Start <= ctx.Caret && ctx.Caret <= r.End {
-				ctx.Operators = append(ctx.Operators, d)
+				ctx.Operators = append(ctx.Operators, o)
 			}
 		}
 	}
@@ -247,10 +454,10 @@ func (d *Dir) ReadParent() (*Dir, error) {
 	if d.parent != nil {
 		return d.parent, nil
 	}
-	if filepath.ToSlash(d.Path()) == "/" {
+	if d.Virtual() || filepath.ToSlash(d.Path()) == "/" {
 		return nil, errors.New("can't read parent")
 	}
-	p, err := NewDir(d.dirname, d.context)
+	p, err := newDir(d.dirname, d.context, d.FileSystem())
 	if err != nil {
 		return nil, err
 	}
@@ -258,7 +465,7 @@ func (d *Dir) ReadParent() (*Dir, error) {
 }
 
 func (d *Dir) HasSelected() bool {
-	if d.selected {
+	if d.Selected() {
 		return true
 	}
 	for _, o := range d.children {
@@ -267,8 +474,8 @@ func (d *Dir) HasSelected() bool {
 			if o.HasSelected() {
 				return true
 			}
-		case *File:
-			if o.selected {
+		default:
+			if o.Selected() {
 				return true
 			}
 		}
@@ -283,24 +490,64 @@ func (d *Dir) All() Operators {
 		switch o := o.(type) {
 		case *Dir:
 			os = append(os, o.All()...)
-		case *File:
+		default:
 			os = append(os, o)
 		}
 	}
 	return os
 }
 
+// selectMatched selects the descendants of d which match.
+// When all is true, the closed directories are opened to find the matched
+// descendants, and closed again unless they contain any.
+// Returns the number of the matched descendants and the newly selected ones.
+func (d *Dir) selectMatched(match func(Operator) bool, all bool) (int, int, error) {
+	matched, selected := 0, 0
+	for _, o := range d.children {
+		if match(o) {
+			matched++
+			if !o.Selected() {
+				o.Select()
+				selected++
+			}
+		}
+		c, ok := o.(*Dir)
+		if !ok || IsArchive(c) {
+			continue
+		}
+		opened := c.opened
+		if !opened {
+			if !all {
+				continue
+			}
+			if err := c.Open(); err != nil {
+				return matched, selected, err
+			}
+		}
+		m, s, err := c.selectMatched(match, all)
+		matched += m
+		selected += s
+		if err != nil {
+			return matched, selected, err
+		}
+		if !opened && m == 0 {
+			c.Close()
+		}
+	}
+	return matched, selected, nil
+}
+
 func (d *Dir) Selecteds() Operators {
 	os := Operators{}
-	if d.selected {
+	if d.Selected() {
 		os = append(os, d)
 	}
 	for _, o := range d.children {
 		switch o := o.(type) {
 		case *Dir:
 			os = append(os, o.Selecteds()...)
-		case *File:
-			if o.selected {
+		default:
+			if o.Selected() {
 				os = append(os, o)
 			}
 		}
@@ -323,18 +570,59 @@ func (d *Dir) Lines(depth int) [][]byte {
 		switch o := o.(type) {
 		case *Dir:
 			lines = append(lines, o.Lines(depth)...)
-		case *File:
-			lines = append(lines, o.line(depth))
+		default:
+			lines = append(lines, fileLine(o, depth))
 		}
 	}
 	return lines
 }
 
+// Rows returns the rows of d and its descendants rendered
+// in the style of Config.RenderStyle.
+func (d *Dir) Rows() [][]byte {
+	g, ok := guides[d.context.Config.RenderStyle]
+	if !ok {
+		return d.Lines(0)
+	}
+	return append([][]byte{d.line(0)}, d.guideLines(g, "")...)
+}
+
+// guideLines returns the rows of the descendants of d
+// drawn with the guides following lead drawn for the ancestors.
+func (d *Dir) guideLines(g guide, lead string) [][]byte {
+	lines := [][]byte{}
+	for i, o := range d.children {
+		branch, next := g.branch, g.vertical
+		if i == len(d.children)-1 {
+			branch, next = g.last, g.space
+		}
+		switch o := o.(type) {
+		case *Dir:
+			lines = append(lines, append([]byte(lead+branch), o.line(1)...))
+			lines = append(lines, o.guideLines(g, lead+next)...)
+		default:
+			lines = append(lines, append([]byte(lead+branch), guideFileLabel(o)...))
+		}
+	}
+	return lines
+}
+
+// guideFileLabel renders o which isn't a directory after the guide.
+// The prefix is shown only for the selected file,
+// since the guide already tells that it is a file.
+func guideFileLabel(o Operator) []byte {
+	if o.Selected() {
+		return fileLine(o, 1)
+	}
+	c := o.Context().Config
+	return []byte(c.icon(o) + displayName(o))
+}
+
 func (d *Dir) line(depth int) []byte {
 	var indent, prefix, delimiter, name, postfix string
 	if depth > 0 {
 		indent = strings.Repeat(d.context.Config.Indent, depth-1)
-		if d.selected {
+		if d.Selected() {
 			prefix = d.context.Config.PrefixSelected
 		} else if d.opened {
 			prefix = d.context.Config.PrefixDirOpened
@@ -343,16 +631,24 @@ func (d *Dir) line(depth int) []byte {
 		}
 		delimiter = " "
 	}
-	name = OriginalPath(d)
+	name = displayName(d)
 	if name != d.context.Config.PostfixDir {
 		postfix = d.context.Config.PostfixDir
 	}
+	name = d.context.Config.icon(d) + name
 	return []byte(indent + prefix + delimiter + name + postfix)
 }
 
 func (d *Dir) CreateDir(name ...string) error {
+	if d.ReadOnly() {
+		return ErrReadOnly
+	}
+	fs := d.FileSystem()
 	for _, n := range name {
-		if err := os.MkdirAll(filepath.Join(d.Path(), n), 0775); err != nil {
+		e := Event{Kind: EventCreate, Dst: filepath.Join(d.Path(), n), FileSystem: fs}
+		if err := d.context.emit(e, func() error {
+			return fs.MkdirAll(e.Dst)
+		}); err != nil {
 			return err
 		}
 	}
@@ -360,8 +656,23 @@ func (d *Dir) CreateDir(name ...string) error {
 }
 
 func (d *Dir) CreateFile(name ...string) error {
+	if d.ReadOnly() {
+		return ErrReadOnly
+	}
+	fs := d.FileSystem()
 	for _, n := range name {
-		if _, err := os.OpenFile(filepath.Join(d.Path(), n), os.O_CREATE, 0664); err != nil {
+		p := filepath.Join(d.Path(), n)
+		if _, err := fs.Lstat(p); err == nil {
+			continue
+		}
+		e := Event{Kind: EventCreate, Dst: p, FileSystem: fs}
+		if err := d.context.emit(e, func() error {
+			f, err := fs.Create(p)
+			if err != nil {
+				return err
+			}
+			return f.Close()
+		}); err != nil {
 			return err
 		}
 	}
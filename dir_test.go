@@ -2,8 +2,12 @@ package tree_test
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	tree "github.com/minodisk/go-tree"
 )
@@ -255,3 +259,64 @@ func TestIndexOf(t *testing.T) {
 		}
 	}
 }
+
+func TestFilterAndSort(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-tree-sort")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.MkdirAll(filepath.Join(dir, "d", "dd"), 0775); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	for i, f := range []string{"b.txt", ".hidden", "a.txt", "c.txt", "d/x.txt"} {
+		p := filepath.Join(dir, f)
+		if err := ioutil.WriteFile(p, []byte(strings.Repeat("x", len(f)*(i+1))), 0664); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(p, now, now.Add(time.Duration(i)*time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, c := range []struct {
+		sortBy  string
+		reverse bool
+		e       string
+	}{
+		{tree.SortName, false, "d dd x.txt a.txt b.txt c.txt"},
+		{tree.SortName, true, "d dd x.txt c.txt b.txt a.txt"},
+		{tree.SortSize, false, "d dd x.txt b.txt a.txt c.txt"},
+		{tree.SortModTime, true, "d dd x.txt c.txt a.txt b.txt"},
+	} {
+		ctx := &tree.Context{
+			Config: &tree.Config{SortBy: c.sortBy, SortReverse: c.reverse},
+			Filter: func(o tree.Operator) bool { return !strings.HasPrefix(o.Name(), ".") },
+		}
+		if err := ctx.Init(); err != nil {
+			t.Fatal(err)
+		}
+		d, err := tree.NewDir(dir, ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := d.OpenDepth(2); err != nil {
+			t.Fatal(err)
+		}
+		names := []string{}
+		for _, o := range d.All()[1:] {
+			names = append(names, o.Name())
+			if o.Name() == "dd" && o.(*tree.Dir).Opened() {
+				t.Errorf("OpenDepth() shouldn't open the directories deeper than the depth")
+			}
+		}
+		if a := strings.Join(names, " "); a != c.e {
+			t.Errorf("%s (reverse: %t) should sort the objects '%s', but '%s'", c.sortBy, c.reverse, c.e, a)
+		}
+	}
+
+	if err := (&tree.Context{Config: &tree.Config{SortBy: "color"}}).Init(); err == nil {
+		t.Errorf("Init() should fail with the unknown sort order")
+	}
+}
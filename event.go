@@ -0,0 +1,82 @@
+package tree
+
+// The kinds of the events emitted when objects change through the tree.
+const (
+	EventCreate  = "create"
+	EventRename  = "rename"
+	EventMove    = "move"
+	EventRemove  = "remove"
+	EventRestore = "restore"
+	EventPaste   = "paste"
+
+	// EventAll subscribes to all kinds of the events.
+	EventAll = "*"
+)
+
+// An Event is an operation on an object.
+// Src is empty for EventCreate.
+// Dst is the path in the trash for EventRemove,
+// or empty when the object is removed permanently.
+type Event struct {
+	Kind       string
+	Src        string
+	Dst        string
+	FileSystem FileSystem
+}
+
+// A Hook is called with the event.
+type Hook func(Event) error
+
+// Before registers the hook called before the operations of the kind.
+// The operation isn't done when the hook returns an error.
+func (c *Context) Before(kind string, hook Hook) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.before == nil {
+		c.before = map[string][]Hook{}
+	}
+	c.before[kind] = append(c.before[kind], hook)
+}
+
+// After registers the hook called after the operations of the kind succeed.
+func (c *Context) After(kind string, hook Hook) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.after == nil {
+		c.after = map[string][]Hook{}
+	}
+	c.after[kind] = append(c.after[kind], hook)
+}
+
+// emit does op between the hooks of the event.
+// All after hooks are called, and the first error of them is returned.
+func (c *Context) emit(e Event, op func() error) error {
+	if c == nil {
+		return op()
+	}
+	for _, h := range c.hooks(c.before, e.Kind) {
+		if err := h(e); err != nil {
+			return err
+		}
+	}
+	if err := op(); err != nil {
+		return err
+	}
+	var err error
+	for _, h := range c.hooks(c.after, e.Kind) {
+		if herr := h(e); herr != nil && err == nil {
+			err = herr
+		}
+	}
+	return err
+}
+
+// hooks returns the hooks of the kind followed by the ones of EventAll.
+// They are copied, so that the hooks can register the others.
+func (c *Context) hooks(m map[string][]Hook, kind string) []Hook {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	hs := []Hook{}
+	hs = append(hs, m[kind]...)
+	return append(hs, m[EventAll]...)
+}
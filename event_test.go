@@ -0,0 +1,157 @@
+package tree_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	tree "github.com/minodisk/go-tree"
+)
+
+func TestHooks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-tree-event")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	root := filepath.Join(dir, "root")
+	if err := os.MkdirAll(filepath.Join(root, "d"), 0775); err != nil {
+		t.Fatal(err)
+	}
+	ctx := &tree.Context{Config: &tree.Config{
+		TrashDirname:   filepath.Join(dir, "trash"),
+		VisitsFilename: filepath.Join(dir, "visits.json"),
+	}}
+	tr, err := tree.New(root, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type event struct{ kind, src, dst string }
+	events := []event{}
+	ctx.After(tree.EventAll, func(e tree.Event) error {
+		rel := func(p string) string {
+			if p == "" {
+				return ""
+			}
+			r, _ := filepath.Rel(dir, p)
+			return filepath.ToSlash(r)
+		}
+		events = append(events, event{e.Kind, rel(e.Src), rel(e.Dst)})
+		return nil
+	})
+	vetoed := errors.New("vetoed")
+	ctx.Before(tree.EventRename, func(e tree.Event) error {
+		if filepath.Base(e.Dst) == "forbidden" {
+			return vetoed
+		}
+		return nil
+	})
+
+	ui := &fakeUI{inputs: []string{"a.txt"}}
+	for _, c := range []struct {
+		command string
+		cursor  int
+		inputs  []string
+		err     error
+	}{
+		{"createFile", 0, []string{"a.txt"}, nil},
+		{"rename", 2, []string{"b.txt"}, nil},
+		{"rename", 2, []string{"forbidden"}, vetoed},
+		{"copy", 2, nil, nil},
+		{"toggle", 1, nil, nil},
+		{"paste", 1, nil, nil},
+		{"move", 3, []string{"d"}, nil},
+		{"remove", 1, nil, nil},
+	} {
+		ui.cursor, ui.inputs, ui.confirm = c.cursor, c.inputs, true
+		if _, err := tr.Dispatch(c.command, ui); err != c.err {
+			t.Fatalf("%s should return %v, but %v", c.command, c.err, err)
+		}
+	}
+	e := []event{
+		{tree.EventCreate, "", "root/a.txt"},
+		{tree.EventRename, "root/a.txt", "root/b.txt"},
+		{tree.EventPaste, "root/b.txt", "root/d/b.txt"},
+		{tree.EventMove, "root/b.txt", "root/d/b.txt"},
+	}
+	if len(events) != len(e)+1 || !reflect.DeepEqual(events[:len(e)], e) {
+		t.Fatalf("the events should be emitted\nexpected: %v\nactual: %v", e, events)
+	}
+	if r := events[len(e)]; r.kind != tree.EventRemove || r.src != "root/d" || filepath.Dir(r.dst) != "trash" {
+		t.Errorf("the remove event should have the path in the trash, but %v", r)
+	}
+	if _, err := os.Stat(filepath.Join(root, "b.txt")); err == nil {
+		t.Errorf("the vetoed operation shouldn't be done")
+	}
+}
+
+func TestHooksVetoOverwrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-tree-event")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	root := filepath.Join(dir, "root")
+	if err := os.MkdirAll(filepath.Join(root, "d"), 0775); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"a.txt", "d/a.txt"} {
+		if err := ioutil.WriteFile(filepath.Join(root, p), []byte(p), 0664); err != nil {
+			t.Fatal(err)
+		}
+	}
+	trash := filepath.Join(dir, "trash")
+	ctx := &tree.Context{Config: &tree.Config{
+		TrashDirname:   trash,
+		VisitsFilename: filepath.Join(dir, "visits.json"),
+	}}
+	tr, err := tree.New(root, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vetoed := errors.New("vetoed")
+	ctx.Before(tree.EventPaste, func(tree.Event) error { return vetoed })
+	ctx.Before(tree.EventRestore, func(tree.Event) error { return vetoed })
+
+	ui := &fakeUI{confirm: true}
+	for _, c := range []struct {
+		command string
+		cursor  int
+		err     error
+	}{
+		{"toggle", 1, nil},
+		{"copy", 3, nil},
+		{"paste", 1, vetoed},
+		{"remove", 2, nil},
+		{"trash", 0, nil},
+		{"restore", 1, vetoed},
+	} {
+		if c.command == "trash" {
+			if err := ioutil.WriteFile(filepath.Join(root, "d/a.txt"), []byte("new"), 0664); err != nil {
+				t.Fatal(err)
+			}
+		}
+		ui.cursor = c.cursor
+		if _, err := tr.Dispatch(c.command, ui); err != c.err {
+			t.Fatalf("%s should return %v, but %v", c.command, c.err, err)
+		}
+	}
+	b, err := ioutil.ReadFile(filepath.Join(root, "d/a.txt"))
+	if err != nil {
+		t.Fatalf("the overwritten object should be left by the vetoed restore: %v", err)
+	}
+	if string(b) != "new" {
+		t.Errorf("the overwritten object should be left by the vetoed paste and restore, but %q", b)
+	}
+	fs, err := ioutil.ReadDir(trash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fs) != 1 {
+		t.Errorf("only the removed object should be in the trash, but %d objects", len(fs))
+	}
+}
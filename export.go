@@ -0,0 +1,312 @@
+package tree
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The formats of Export.
+const (
+	FormatJSON     = "json"
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+)
+
+// ExportOptions decides the objects and the fields exported.
+type ExportOptions struct {
+	// Recursive exports all descendants read from the file system
+	// instead of the objects shown in the tree.
+	Recursive bool
+	// Depth limits the depth of the exported descendants.
+	// Zero means no limit.
+	Depth int
+	// Include exports only the files whose names match any of the glob patterns.
+	Include []string
+	// Exclude doesn't export the objects whose names match any of the glob patterns.
+	Exclude []string
+	// Size exports the size of the objects.
+	Size bool
+	// ModTime exports the modification time of the objects.
+	ModTime bool
+}
+
+// TimeFormat is the format of the modification time in the exported tree,
+// which is the same as the one of the tree command.
+const TimeFormat = "Jan _2 15:04"
+
+// An exportNode is an object to export with the descendants.
+type exportNode struct {
+	o        Operator
+	dir      bool
+	children []*exportNode
+}
+
+// Export writes the tree of the roots in the format.
+func Export(w io.Writer, roots []*Dir, format string, opts ExportOptions) error {
+	nodes := []*exportNode{}
+	for _, r := range roots {
+		n, err := newExportNode(r, opts, 0)
+		if err != nil {
+			return err
+		}
+		nodes = append(nodes, n)
+	}
+	switch format {
+	case FormatJSON:
+		return exportJSON(w, nodes, opts)
+	case FormatMarkdown:
+		return exportMarkdown(w, nodes, opts)
+	case FormatHTML:
+		return exportHTML(w, nodes, opts)
+	default:
+		return fmt.Errorf("unknown format '%s'", format)
+	}
+}
+
+func newExportNode(d *Dir, opts ExportOptions, depth int) (*exportNode, error) {
+	n := &exportNode{o: d, dir: true}
+	if opts.Depth > 0 && depth >= opts.Depth {
+		return n, nil
+	}
+	children := d.children
+	if opts.Recursive {
+		if IsArchive(d) {
+			return n, nil
+		}
+		cs, err := d.read()
+		if err != nil {
+			return nil, err
+		}
+		children = Operators{}
+		for _, c := range cs {
+			if f := d.context.Filter; f == nil || f(c) {
+				children = append(children, c)
+			}
+		}
+		d.context.Config.sort(children)
+	}
+	for _, o := range children {
+		if matchAny(opts.Exclude, o.Name()) {
+			continue
+		}
+		// Archives are exported as files unless opened in the tree.
+		if c, ok := o.(*Dir); ok && (!IsArchive(c) || !opts.Recursive && c.Opened()) {
+			cn, err := newExportNode(c, opts, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			n.children = append(n.children, cn)
+			continue
+		}
+		if len(opts.Include) > 0 && !matchAny(opts.Include, o.Name()) {
+			continue
+		}
+		n.children = append(n.children, &exportNode{o: o})
+	}
+	return n, nil
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := filepath.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// count returns the numbers of the directories and the files under n.
+func (n *exportNode) count() (int, int) {
+	dirs, files := 0, 0
+	for _, c := range n.children {
+		if c.dir {
+			d, f := c.count()
+			dirs += d + 1
+			files += f
+		} else {
+			files++
+		}
+	}
+	return dirs, files
+}
+
+// metadata returns the size and the modification time of n as the options.
+func (n *exportNode) metadata(opts ExportOptions) []string {
+	info, ok := n.o.(os.FileInfo)
+	if !ok {
+		return nil
+	}
+	ms := []string{}
+	if opts.Size {
+		ms = append(ms, fmt.Sprintf("%d B", info.Size()))
+	}
+	if opts.ModTime {
+		ms = append(ms, info.ModTime().Format(TimeFormat))
+	}
+	return ms
+}
+
+func (n *exportNode) name(root bool) string {
+	if root {
+		return n.o.Path()
+	}
+	return n.o.Name()
+}
+
+type jsonNode struct {
+	Type     string       `json:"type"`
+	Name     string       `json:"name,omitempty"`
+	Size     *int64       `json:"size,omitempty"`
+	Time     string       `json:"time,omitempty"`
+	Contents *[]*jsonNode `json:"contents,omitempty"`
+
+	Directories *int `json:"directories,omitempty"`
+	Files       *int `json:"files,omitempty"`
+}
+
+// exportJSON writes the nodes in the format of the tree command with -J.
+func exportJSON(w io.Writer, nodes []*exportNode, opts ExportOptions) error {
+	js := []*jsonNode{}
+	dirs, files := 0, 0
+	for _, n := range nodes {
+		js = append(js, n.json(true, opts))
+		d, f := n.count()
+		dirs += d
+		files += f
+	}
+	js = append(js, &jsonNode{Type: "report", Directories: &dirs, Files: &files})
+	b, err := json.MarshalIndent(js, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+func (n *exportNode) json(root bool, opts ExportOptions) *jsonNode {
+	j := &jsonNode{Type: "file", Name: n.name(root)}
+	if n.dir {
+		// Directories have the contents even if empty like the tree command.
+		j.Type = "directory"
+		cs := []*jsonNode{}
+		for _, c := range n.children {
+			cs = append(cs, c.json(false, opts))
+		}
+		j.Contents = &cs
+	}
+	if info, ok := n.o.(os.FileInfo); ok {
+		if opts.Size {
+			size := info.Size()
+			j.Size = &size
+		}
+		if opts.ModTime {
+			j.Time = info.ModTime().Format(TimeFormat)
+		}
+	}
+	return j
+}
+
+// exportMarkdown writes the nodes as the nested list of Markdown.
+func exportMarkdown(w io.Writer, nodes []*exportNode, opts ExportOptions) error {
+	for _, n := range nodes {
+		if err := n.markdown(w, 0, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *exportNode) markdown(w io.Writer, depth int, opts ExportOptions) error {
+	name := n.name(depth == 0)
+	if n.dir {
+		name += "/"
+	}
+	line := strings.Repeat("  ", depth) + "- " + markdownEscaper.Replace(name)
+	if ms := n.metadata(opts); len(ms) > 0 {
+		line += " (" + strings.Join(ms, ", ") + ")"
+	}
+	if _, err := io.WriteString(w, line+"\n"); err != nil {
+		return err
+	}
+	for _, c := range n.children {
+		if err := c.markdown(w, depth+1, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`,
+)
+
+// exportHTML writes the nodes as the standalone HTML document.
+func exportHTML(w io.Writer, nodes []*exportNode, opts ExportOptions) error {
+	title := []string{}
+	for _, n := range nodes {
+		title = append(title, n.name(true))
+	}
+	if _, err := fmt.Fprintf(w, htmlHeader, html.EscapeString(strings.Join(title, ", "))); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, "<ul>\n"); err != nil {
+		return err
+	}
+	for _, n := range nodes {
+		if err := n.html(w, true, opts); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "</ul>\n</body>\n</html>\n")
+	return err
+}
+
+func (n *exportNode) html(w io.Writer, root bool, opts ExportOptions) error {
+	class, name := "file", n.name(root)
+	if n.dir {
+		class, name = "directory", name+"/"
+	}
+	item := fmt.Sprintf(`<li class="%s">%s`, class, html.EscapeString(name))
+	if ms := n.metadata(opts); len(ms) > 0 {
+		item += ` <span class="meta">` + html.EscapeString(strings.Join(ms, ", ")) + `</span>`
+	}
+	if _, err := io.WriteString(w, item); err != nil {
+		return err
+	}
+	if len(n.children) > 0 {
+		if _, err := io.WriteString(w, "\n<ul>\n"); err != nil {
+			return err
+		}
+		for _, c := range n.children {
+			if err := c.html(w, false, opts); err != nil {
+				return err
+			}
+		}
+		if _, err := io.WriteString(w, "</ul>\n"); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "</li>\n")
+	return err
+}
+
+const htmlHeader = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>%s</title>
+<style>
+body { font-family: monospace; }
+ul { list-style: none; padding-left: 1.5em; }
+.directory { font-weight: bold; }
+.file { font-weight: normal; }
+.meta { color: #888; }
+</style>
+</head>
+<body>
+`
@@ -0,0 +1,116 @@
+package tree_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tree "github.com/minodisk/go-tree"
+)
+
+func TestExport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-tree-export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	root := filepath.Join(dir, "root")
+	if err := os.MkdirAll(filepath.Join(root, "a", "aa"), 0775); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{"a/aa/deep.go", "a/x.go", "a/x_test.go", "b<c>.md", "z.txt"} {
+		if err := ioutil.WriteFile(filepath.Join(root, f), []byte("12345"), 0664); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tr, err := tree.New(root, &tree.Context{Config: &tree.Config{
+		TrashDirname:   filepath.Join(dir, "trash"),
+		VisitsFilename: filepath.Join(dir, "visits.json"),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if err := tr.Export(&b, tree.FormatMarkdown, tree.ExportOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	e := root + `/
+  - a/
+  - b\<c\>.md
+  - z.txt
+`
+	if a := b.String(); a != "- "+e {
+		t.Errorf("Export() should export the shown objects as Markdown\nexpected:\n- %s\nactual:\n%s", e, a)
+	}
+
+	b.Reset()
+	opts := tree.ExportOptions{Recursive: true, Depth: 2, Exclude: []string{"*_test.go"}, Include: []string{"*.go"}, Size: true}
+	if err := tr.Export(&b, tree.FormatJSON, opts); err != nil {
+		t.Fatal(err)
+	}
+	var js []map[string]interface{}
+	if err := json.Unmarshal(b.Bytes(), &js); err != nil {
+		t.Fatalf("Export() should export JSON: %s\n%s", err, b.String())
+	}
+	if len(js) != 2 || js[1]["type"] != "report" || js[1]["directories"] != 2.0 || js[1]["files"] != 1.0 {
+		t.Fatalf("Export() should export the report like tree -J, but exports %s", b.String())
+	}
+	a := js[0]["contents"].([]interface{})[0].(map[string]interface{})
+	if a["name"] != "a" || a["type"] != "directory" || len(a["contents"].([]interface{})) != 2 {
+		t.Errorf("Export() should filter the objects and limit the depth, but exports %s", b.String())
+	}
+	if x := a["contents"].([]interface{})[1].(map[string]interface{}); x["name"] != "x.go" || x["size"] != 5.0 {
+		t.Errorf("Export() should export the size, but exports %v", x)
+	}
+	if aa := a["contents"].([]interface{})[0].(map[string]interface{}); aa["contents"] == nil || len(aa["contents"].([]interface{})) != 0 {
+		t.Errorf("Export() should export the empty contents of the depth-limited directory, but exports %v", aa)
+	}
+	if x := a["contents"].([]interface{})[1].(map[string]interface{}); x["contents"] != nil {
+		t.Errorf("Export() shouldn't export the contents of the file, but exports %v", x)
+	}
+
+	b.Reset()
+	if err := tr.Export(&b, tree.FormatHTML, tree.ExportOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if a := b.String(); !strings.HasPrefix(a, "<!DOCTYPE html>") || !strings.Contains(a, `<li class="file">b&lt;c&gt;.md</li>`) {
+		t.Errorf("Export() should export the standalone HTML, but exports:\n%s", a)
+	}
+	if err := tr.Export(&b, "pdf", tree.ExportOptions{}); err == nil {
+		t.Errorf("Export() should fail with the unknown format")
+	}
+}
+
+func TestExportArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-tree-export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.MkdirAll(filepath.Join(dir, "d"), 0775); err != nil {
+		t.Fatal(err)
+	}
+	writeZip(t, filepath.Join(dir, "p.zip"), map[string]string{"a.txt": "a"})
+	tr, clean := newTree(t, dir)
+	defer clean()
+
+	var b bytes.Buffer
+	if err := tr.Export(&b, tree.FormatJSON, tree.ExportOptions{Recursive: true}); err != nil {
+		t.Fatal(err)
+	}
+	var js []map[string]interface{}
+	if err := json.Unmarshal(b.Bytes(), &js); err != nil {
+		t.Fatal(err)
+	}
+	if js[1]["directories"] != 1.0 || js[1]["files"] != 1.0 {
+		t.Errorf("Export() should count the archive as a file, but exports %s", b.String())
+	}
+	if p := js[0]["contents"].([]interface{})[1].(map[string]interface{}); p["name"] != "p.zip" || p["type"] != "file" {
+		t.Errorf("Export() should export the archive as a file, but exports %v", p)
+	}
+}
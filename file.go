@@ -13,17 +13,21 @@ type File struct {
 	os.FileInfo
 	dirname string
 	parent  *Dir
-
-	selected bool
+	fs      FileSystem
 }
 
 func NewFile(path string, context *Context) (*File, error) {
+	return newFile(path, context, LocalFS)
+}
+
+func newFile(path string, context *Context, fs FileSystem) (*File, error) {
 	var err error
 	f := &File{
 		context: context,
 		dirname: filepath.Dir(path),
+		fs:      fs,
 	}
-	f.FileInfo, err = os.Stat(path)
+	f.FileInfo, err = fs.Stat(path)
 	if err != nil {
 		return nil, err
 	}
@@ -53,35 +57,46 @@ func (f *File) Path() string {
 	return filepath.Join(f.dirname, f.Name())
 }
 
+func (f *File) FileSystem() FileSystem {
+	if f.fs == nil {
+		return LocalFS
+	}
+	return f.fs
+}
+
 func (f *File) Selected() bool {
-	return f.selected
+	return f.context.isSelected(f)
 }
 
 func (f *File) Select() {
-	f.selected = true
+	f.context.selectOperator(f)
 }
 
 func (f *File) Unselect() {
-	f.selected = false
+	f.context.unselectOperator(f)
 }
 
 func (f *File) ToggleSelected() {
-	f.selected = !f.selected
+	if f.Selected() {
+		f.Unselect()
+	} else {
+		f.Select()
+	}
 }
 
-// Non interface methods
-
-func (f *File) line(depth int) []byte {
+// fileLine renders o which isn't a directory.
+func fileLine(o Operator, depth int) []byte {
+	c := o.Context().Config
 	var indent, prefix, delimiter, name string
 	if depth > 0 {
-		indent = strings.Repeat(f.context.Config.Indent, depth-1)
-		if f.selected {
-			prefix = f.context.Config.PrefixSelected
+		indent = strings.Repeat(c.Indent, depth-1)
+		if o.Selected() {
+			prefix = c.PrefixSelected
 		} else {
-			prefix = f.context.Config.PrefixFile
+			prefix = c.PrefixFile
 		}
 		delimiter = " "
 	}
-	name = OriginalPath(f)
+	name = c.icon(o) + displayName(o)
 	return []byte(indent + prefix + delimiter + name)
 }
@@ -0,0 +1,252 @@
+package tree
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// visitsMaxRank is the total of the ranks above which the visits are aged.
+const visitsMaxRank = 10000
+
+// A Visit is the record of visiting the directory at Path.
+type Visit struct {
+	Path        string
+	Rank        float64
+	LastVisited time.Time
+}
+
+// Score returns the frecency of v at now.
+// Recently visited directories get higher scores than the others
+// visited as often.
+func (v Visit) Score(now time.Time) float64 {
+	d := now.Sub(v.LastVisited)
+	switch {
+	case d < time.Hour:
+		return v.Rank * 4
+	case d < 24*time.Hour:
+		return v.Rank * 2
+	case d < 7*24*time.Hour:
+		return v.Rank / 2
+	default:
+		return v.Rank / 4
+	}
+}
+
+// Matches returns that all keywords appear in the path of v in order,
+// and the last keyword appears in the base name of it.
+// Keywords are matched case-insensitively.
+func (v Visit) Matches(keywords []string) bool {
+	if len(keywords) == 0 {
+		return true
+	}
+	p := strings.ToLower(v.Path)
+	for _, k := range keywords {
+		i := strings.Index(p, strings.ToLower(k))
+		if i == -1 {
+			return false
+		}
+		p = p[i+len(k):]
+	}
+	last := strings.ToLower(keywords[len(keywords)-1])
+	return strings.Contains(strings.ToLower(filepath.Base(v.Path)), last)
+}
+
+// Visits is a list of Visit.
+type Visits []Visit
+
+// LoadVisits reads the visits from the file.
+// When the file doesn't exist, returns no visits.
+func LoadVisits(filename string) (Visits, error) {
+	vs := Visits{}
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return vs, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &vs); err != nil {
+		return nil, err
+	}
+	return vs, nil
+}
+
+// Save writes the visits into the file.
+func (vs Visits) Save(filename string) error {
+	b, err := json.MarshalIndent(vs, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filename, b)
+}
+
+// loadVisits returns the visits read from VisitsFilename once per Context.
+// It must be called with c.mu held.
+func (c *Context) loadVisits() (Visits, error) {
+	if !c.visitsLoaded {
+		vs, err := LoadVisits(c.Config.VisitsFilename)
+		if err != nil {
+			return nil, err
+		}
+		c.visits, c.visitsLoaded = vs, true
+	}
+	return c.visits, nil
+}
+
+// saveVisits writes the visits not written yet into VisitsFilename.
+// It must be called with c.mu held.
+func (c *Context) saveVisits() error {
+	if c.unsaved == 0 {
+		return nil
+	}
+	if err := c.visits.Save(c.Config.VisitsFilename); err != nil {
+		return err
+	}
+	c.unsaved = 0
+	return nil
+}
+
+// keepVisitsErr keeps the first error of recording the visits.
+// It must be called with c.mu held.
+func (c *Context) keepVisitsErr(err error) {
+	if c.visitsErr == nil {
+		c.visitsErr = err
+	}
+}
+
+// visited returns the recorded visits.
+func (c *Context) visited() (Visits, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.loadVisits()
+}
+
+// visit records the visit to the directory at p at now and writes the visits.
+// Failing to record never prevents the navigation,
+// so the first error is kept to be returned by FlushVisits.
+func (c *Context) visit(p string, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	vs, err := c.loadVisits()
+	if err != nil {
+		c.keepVisitsErr(err)
+		return
+	}
+	c.visits = vs.Visit(p, now)
+	c.unsaved++
+	c.keepVisitsErr(c.saveVisits())
+}
+
+// forget removes the visit to the directory at p and writes the visits.
+func (c *Context) forget(p string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	vs, err := c.loadVisits()
+	if err != nil {
+		return err
+	}
+	c.visits = vs.Remove(p)
+	c.unsaved++
+	return c.saveVisits()
+}
+
+// FlushVisits writes the visits which failed to be written,
+// and returns the first error of recording the visits since the last call.
+func (c *Context) FlushVisits() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.keepVisitsErr(c.saveVisits())
+	err := c.visitsErr
+	c.visitsErr = nil
+	return err
+}
+
+// Visit returns the visits with the visit to the directory at p at now.
+// When the total of the ranks exceeds the limit, all ranks are decayed
+// and the visits which are rarely used are forgotten.
+func (vs Visits) Visit(p string, now time.Time) Visits {
+	visited := make(Visits, 0, len(vs)+1)
+	found := false
+	total := 0.0
+	for _, v := range vs {
+		if v.Path == p {
+			v.Rank++
+			v.LastVisited = now
+			found = true
+		}
+		total += v.Rank
+		visited = append(visited, v)
+	}
+	if !found {
+		visited = append(visited, Visit{Path: p, Rank: 1, LastVisited: now})
+		total++
+	}
+	if total <= visitsMaxRank {
+		return visited
+	}
+
+	aged := Visits{}
+	for _, v := range visited {
+		v.Rank *= 0.9 * visitsMaxRank / total
+		if v.Rank >= 1 {
+			aged = append(aged, v)
+		}
+	}
+	return aged
+}
+
+// Remove returns the visits without the one to p.
+func (vs Visits) Remove(p string) Visits {
+	removed := Visits{}
+	for _, v := range vs {
+		if v.Path != p {
+			removed = append(removed, v)
+		}
+	}
+	return removed
+}
+
+// Rank returns the visits matching the query ordered by the score at now.
+// The query is split into keywords with white spaces.
+func (vs Visits) Rank(query string, now time.Time) Visits {
+	keywords := strings.Fields(query)
+	ranked := Visits{}
+	for _, v := range vs {
+		if v.Matches(keywords) {
+			ranked = append(ranked, v)
+		}
+	}
+	sort.Stable(byScore{ranked, now})
+	return ranked
+}
+
+type byScore struct {
+	Visits
+	now time.Time
+}
+
+func (s byScore) Len() int {
+	return len(s.Visits)
+}
+
+func (s byScore) Swap(i, j int) {
+	s.Visits[i], s.Visits[j] = s.Visits[j], s.Visits[i]
+}
+
+func (s byScore) Less(i, j int) bool {
+	return s.Visits[i].Score(s.now) > s.Visits[j].Score(s.now)
+}
+
+// Paths returns the paths of the visits.
+func (vs Visits) Paths() []string {
+	ps := make([]string, len(vs))
+	for i, v := range vs {
+		ps[i] = v.Path
+	}
+	return ps
+}
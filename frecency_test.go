@@ -0,0 +1,152 @@
+package tree_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	tree "github.com/minodisk/go-tree"
+)
+
+func TestVisits(t *testing.T) {
+	now := time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+	vs := tree.Visits{
+		{Path: "/home/foo/src/project", Rank: 10, LastVisited: now.Add(-30 * 24 * time.Hour)},
+		{Path: "/home/foo/work/project", Rank: 2, LastVisited: now.Add(-10 * time.Minute)},
+		{Path: "/home/foo/project/docs", Rank: 50, LastVisited: now},
+	}
+
+	ranked := vs.Rank("proj", now)
+	if a, e := ranked.Paths(), []string{"/home/foo/work/project", "/home/foo/src/project"}; len(a) != len(e) || a[0] != e[0] || a[1] != e[1] {
+		t.Errorf("Rank() should order the visits matching the base name by frecency, expected %v, but actual %v", e, a)
+	}
+	if a, e := vs.Rank("SRC proj", now).Paths(), []string{"/home/foo/src/project"}; len(a) != len(e) || a[0] != e[0] {
+		t.Errorf("Rank() should match the keywords in order, expected %v, but actual %v", e, a)
+	}
+	if a, e := len(vs.Rank("proj src", now)), 0; a != e {
+		t.Errorf("Rank() shouldn't match the keywords out of order, but matches %d visits", a)
+	}
+
+	vs = vs.Visit("/home/foo/work/project", now).Visit("/tmp", now)
+	if a, e := len(vs), 4; a != e {
+		t.Fatalf("Visit() should add the new path, expected %d visits, but actual %d", e, a)
+	}
+	if a, e := vs[1].Rank, 3.0; a != e {
+		t.Errorf("Visit() should increase the rank, expected %f, but actual %f", e, a)
+	}
+
+	vs = tree.Visits{
+		{Path: "/often", Rank: 9999, LastVisited: now},
+		{Path: "/rare", Rank: 1, LastVisited: now},
+	}
+	vs = vs.Visit("/often", now)
+	if a, e := vs.Paths(), []string{"/often"}; len(a) != len(e) || a[0] != e[0] {
+		t.Errorf("Visit() should age the visits and forget rare ones, expected %v, but actual %v", e, a)
+	}
+	if vs[0].Rank >= 10000 {
+		t.Errorf("Visit() should decay the ranks, but the rank is %f", vs[0].Rank)
+	}
+}
+
+func TestJump(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-tree-jump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, d := range []string{"alpha", "beta", "gone"} {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0775); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tr, clean := newTree(t, dir)
+	defer clean()
+
+	for _, d := range []string{"alpha", "gone", "gone", "beta"} {
+		if err := tr.CD(cursorAt(0), textOf(filepath.Join(dir, d)), noRender); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Remove(filepath.Join(dir, "gone")); err != nil {
+		t.Fatal(err)
+	}
+
+	var listed tree.Visits
+	if err := tr.Visits(textOf(""), func(vs tree.Visits) error {
+		listed = vs
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if a, e := len(listed), 4; a != e {
+		t.Fatalf("Visits() should list every visited directory, expected %d, but actual %d", e, a)
+	}
+	if a, e := listed[0].Path, filepath.Join(dir, "gone"); a != e {
+		t.Errorf("Visits() should list the most frecent first, expected '%s', but actual '%s'", e, a)
+	}
+
+	if err := tr.Jump(cursorAt(0), textOf("alp"), nil, noRender); err != nil {
+		t.Fatal(err)
+	}
+	if a, e := tr.Roots()[0].Path(), filepath.Join(dir, "alpha"); a != e {
+		t.Errorf("Jump() should set the matching directory as root, expected '%s', but actual '%s'", e, a)
+	}
+	if err := tr.Jump(cursorAt(0), textOf("gone"), nil, noRender); err == nil {
+		t.Errorf("Jump() shouldn't set the disappeared directory as root")
+	}
+	if err := tr.Visits(textOf("gone"), func(vs tree.Visits) error {
+		if len(vs) != 0 {
+			t.Errorf("Jump() should forget the disappeared directory")
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFlushVisits(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-tree-visits")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, d := range []string{"alpha", "beta"} {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0775); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "file"), []byte{}, 0664); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		filename string
+		visits   int
+		fails    bool
+	}{
+		{filepath.Join(dir, "data", "visits.json"), 3, false},
+		{filepath.Join(dir, "file", "visits.json"), 0, true},
+	} {
+		ctx := &tree.Context{Config: &tree.Config{
+			TrashDirname:   filepath.Join(dir, "trash"),
+			VisitsFilename: c.filename,
+		}}
+		tr, err := tree.New(dir, ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, d := range []string{"alpha", "beta"} {
+			if err := tr.CD(cursorAt(0), textOf(filepath.Join(dir, d)), noRender); err != nil {
+				t.Fatalf("failing to record the visit shouldn't prevent the navigation: %v", err)
+			}
+		}
+		if vs, err := tree.LoadVisits(c.filename); !c.fails && (err != nil || len(vs) != c.visits) {
+			t.Errorf("the visits should be written on each visit, expected %d, but %d (%v)", c.visits, len(vs), err)
+		}
+		if err := ctx.FlushVisits(); (err != nil) != c.fails {
+			t.Errorf("FlushVisits() should return the error of recording the visits with '%s', but %v", c.filename, err)
+		}
+	}
+}
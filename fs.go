@@ -0,0 +1,74 @@
+package tree
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// A FileSystem reads and writes objects on a storage,
+// such as local disk or remote server.
+type FileSystem interface {
+	Stat(name string) (os.FileInfo, error)
+	Lstat(name string) (os.FileInfo, error)
+	ReadDir(dirname string) ([]os.FileInfo, error)
+	Open(name string) (io.ReadCloser, error)
+	// Create opens the file for writing.
+	// The file is created if it doesn't exist, or truncated.
+	Create(name string) (io.WriteCloser, error)
+	MkdirAll(path string) error
+	Rename(oldpath, newpath string) error
+	RemoveAll(path string) error
+}
+
+// LocalFS is the FileSystem of local disk.
+var LocalFS FileSystem = localFS{}
+
+type localFS struct{}
+
+func (localFS) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (localFS) Lstat(name string) (os.FileInfo, error) {
+	return os.Lstat(name)
+}
+
+func (localFS) ReadDir(dirname string) ([]os.FileInfo, error) {
+	return ioutil.ReadDir(dirname)
+}
+
+func (localFS) Open(name string) (io.ReadCloser, error) {
+	return os.Open(name)
+}
+
+func (localFS) Create(name string) (io.WriteCloser, error) {
+	return os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0664)
+}
+
+func (localFS) MkdirAll(path string) error {
+	return os.MkdirAll(path, 0775)
+}
+
+func (localFS) Rename(oldpath, newpath string) error {
+	return os.Rename(oldpath, newpath)
+}
+
+func (localFS) RemoveAll(path string) error {
+	return os.RemoveAll(path)
+}
+
+// FileSystemOf returns the FileSystem where o exists.
+func FileSystemOf(o Operator) FileSystem {
+	if f, ok := o.(interface {
+		FileSystem() FileSystem
+	}); ok {
+		return f.FileSystem()
+	}
+	return LocalFS
+}
+
+// IsLocal returns that o exists on local disk.
+func IsLocal(o Operator) bool {
+	return FileSystemOf(o) == LocalFS
+}
@@ -2,6 +2,7 @@ package tree
 
 import (
 	"io/ioutil"
+	"os"
 	"os/user"
 	"path/filepath"
 	"regexp"
@@ -35,3 +36,26 @@ func DirProject(dirname string, rProject *regexp.Regexp) (string, error) {
 	}
 	return DirProject(parent, rProject)
 }
+
+// writeFileAtomic writes b into the file replacing it at once,
+// so other processes never read it half written.
+func writeFileAtomic(filename string, b []byte) error {
+	dirname := filepath.Dir(filename)
+	if err := os.MkdirAll(dirname, 0775); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(dirname, filepath.Base(filename))
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), filename)
+}
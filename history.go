@@ -0,0 +1,37 @@
+package tree
+
+// historySize is the maximum number of the entries kept in each direction.
+const historySize = 100
+
+// A historyEntry is a snapshot of the roots of a Tree.
+type historyEntry struct {
+	roots  []*Dir
+	opened [][]string
+	cursor int
+}
+
+// history keeps the entries to go back and forward.
+type history struct {
+	backs    []historyEntry
+	forwards []historyEntry
+}
+
+func (h *history) push(e historyEntry) {
+	h.backs = pushEntry(h.backs, e)
+	h.forwards = nil
+}
+
+func pushEntry(es []historyEntry, e historyEntry) []historyEntry {
+	es = append(es, e)
+	if len(es) > historySize {
+		es = es[len(es)-historySize:]
+	}
+	return es
+}
+
+func popEntry(es []historyEntry) ([]historyEntry, historyEntry, bool) {
+	if len(es) == 0 {
+		return es, historyEntry{}, false
+	}
+	return es[:len(es)-1], es[len(es)-1], true
+}
@@ -0,0 +1,81 @@
+package tree
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The default icons are the glyphs of Nerd Fonts.
+var (
+	IconsByNameDefault = map[string]string{
+		".gitignore": "\uf1d3",
+		"Dockerfile": "\uf308",
+		"LICENSE":    "\uf718",
+		"Makefile":   "\uf489",
+		"README.md":  "\uf48a",
+	}
+	IconsByExtensionDefault = map[string]string{
+		"bz2":  "\uf410",
+		"c":    "\ue61e",
+		"css":  "\ue749",
+		"gif":  "\uf1c5",
+		"go":   "\ue626",
+		"gz":   "\uf410",
+		"html": "\uf13b",
+		"jpeg": "\uf1c5",
+		"jpg":  "\uf1c5",
+		"js":   "\ue74e",
+		"json": "\ue60b",
+		"md":   "\uf48a",
+		"png":  "\uf1c5",
+		"py":   "\ue606",
+		"rb":   "\ue21e",
+		"rs":   "\ue7a8",
+		"sh":   "\uf489",
+		"tar":  "\uf410",
+		"txt":  "\uf15c",
+		"xz":   "\uf410",
+		"yaml": "\uf481",
+		"yml":  "\uf481",
+		"zip":  "\uf410",
+	}
+)
+
+// icon returns the icon of o followed by a space,
+// or the empty string when icons aren't shown.
+func (c *Config) icon(o Operator) string {
+	if !c.Icons {
+		return ""
+	}
+	if d, ok := o.(*Dir); ok && !IsArchive(d) {
+		if d.Opened() {
+			return c.IconDirOpened + " "
+		}
+		return c.IconDirClosed + " "
+	}
+	name := o.Name()
+	if i, ok := c.IconsByName[name]; ok {
+		return i + " "
+	}
+	if info, ok := o.(os.FileInfo); ok && info.Mode()&os.ModeSymlink != 0 {
+		return c.IconSymlink + " "
+	}
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(name), "."))
+	if i, ok := c.IconsByExtension[ext]; ok {
+		return i + " "
+	}
+	return c.IconFile + " "
+}
+
+// mergeIcons returns the default icons overridden by icons.
+func mergeIcons(defaults, icons map[string]string) map[string]string {
+	merged := map[string]string{}
+	for k, v := range defaults {
+		merged[k] = v
+	}
+	for k, v := range icons {
+		merged[k] = v
+	}
+	return merged
+}
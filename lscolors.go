@@ -0,0 +1,123 @@
+package tree
+
+import (
+	"os"
+	"strings"
+)
+
+// LSColorsDefault is used when LS_COLORS isn't set.
+// It is a part of the default of dircolors.
+const LSColorsDefault = "di=01;34:ln=01;36:so=01;35:pi=40;33:bd=40;33;01:cd=40;33;01:or=40;31;01:ex=01;32:" +
+	"*.tar=01;31:*.tgz=01;31:*.gz=01;31:*.bz2=01;31:*.xz=01;31:*.zip=01;31:" +
+	"*.jpg=01;35:*.jpeg=01;35:*.gif=01;35:*.png=01;35"
+
+// The highlight groups assigned to objects.
+const (
+	GroupDirectory  = "directory"
+	GroupSymlink    = "symlink"
+	GroupOrphan     = "orphan"
+	GroupPipe       = "pipe"
+	GroupSocket     = "socket"
+	GroupDevice     = "device"
+	GroupExecutable = "executable"
+	GroupArchive    = "archive"
+	GroupFile       = "file"
+)
+
+// LSColors is the colors of objects parsed from LS_COLORS.
+type LSColors struct {
+	types    map[string]string
+	patterns map[string]string
+}
+
+// ParseLSColors parses s formatted like LS_COLORS.
+// Invalid entries are ignored.
+func ParseLSColors(s string) *LSColors {
+	c := &LSColors{types: map[string]string{}, patterns: map[string]string{}}
+	for _, e := range strings.Split(s, ":") {
+		kv := strings.SplitN(e, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			continue
+		}
+		if strings.HasPrefix(kv[0], "*") {
+			c.patterns[kv[0][1:]] = kv[1]
+		} else {
+			c.types[kv[0]] = kv[1]
+		}
+	}
+	return c
+}
+
+// Highlight returns the highlight group of o and the SGR parameters
+// of the color, like "01;34", which is empty when no color is assigned.
+func (c *LSColors) Highlight(o Operator) (string, string) {
+	group, key := groupOf(o)
+	if c == nil {
+		return group, ""
+	}
+	color := c.types[key]
+	switch group {
+	case GroupFile, GroupArchive:
+		if p, ok := c.pattern(o.Name()); ok {
+			color = p
+		}
+	}
+	return group, color
+}
+
+// pattern returns the color of the longest pattern matching the name.
+func (c *LSColors) pattern(name string) (string, bool) {
+	color, length := "", -1
+	for p, col := range c.patterns {
+		if len(p) > length && strings.HasSuffix(name, p) {
+			color, length = col, len(p)
+		}
+	}
+	return color, length != -1
+}
+
+// groupOf returns the highlight group of o and the key of it in LS_COLORS.
+func groupOf(o Operator) (string, string) {
+	if d, ok := o.(*Dir); ok {
+		if IsArchive(d) {
+			return GroupArchive, "fi"
+		}
+		return GroupDirectory, "di"
+	}
+	info, ok := o.(os.FileInfo)
+	if !ok {
+		return GroupFile, "fi"
+	}
+	mode := info.Mode()
+	switch {
+	case mode&os.ModeSymlink != 0:
+		if _, err := FileSystemOf(o).Stat(o.Path()); err != nil {
+			return GroupOrphan, "or"
+		}
+		return GroupSymlink, "ln"
+	case mode&os.ModeNamedPipe != 0:
+		return GroupPipe, "pi"
+	case mode&os.ModeSocket != 0:
+		return GroupSocket, "so"
+	case mode&os.ModeCharDevice != 0:
+		return GroupDevice, "cd"
+	case mode&os.ModeDevice != 0:
+		return GroupDevice, "bd"
+	case mode&0111 != 0:
+		return GroupExecutable, "ex"
+	case ArchiveFormat(o.Name()) != "":
+		return GroupArchive, "fi"
+	default:
+		return GroupFile, "fi"
+	}
+}
+
+// A Highlight is the range of the name in a rendered row
+// with the highlight group and the color of the object.
+type Highlight struct {
+	Row   int
+	Start int
+	End   int
+	Group string
+	Color string
+}
@@ -0,0 +1,87 @@
+package tree_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	tree "github.com/minodisk/go-tree"
+)
+
+func TestHighlights(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-tree-highlight")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	root := filepath.Join(dir, "root")
+	if err := os.MkdirAll(filepath.Join(root, "d"), 0775); err != nil {
+		t.Fatal(err)
+	}
+	for name, mode := range map[string]os.FileMode{
+		"a.go":   0664,
+		"b.png":  0664,
+		"run.sh": 0775,
+	} {
+		if err := ioutil.WriteFile(filepath.Join(root, name), []byte{}, mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeTarGz(t, filepath.Join(root, "c.tar.gz"), map[string]string{"x.txt": "x"})
+	if err := os.Symlink(filepath.Join(root, "a.go"), filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(root, "gone"), filepath.Join(root, "orphan")); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &tree.Context{Config: &tree.Config{
+		TrashDirname:     filepath.Join(dir, "trash"),
+		VisitsFilename:   filepath.Join(dir, "visits.json"),
+		Icons:            true,
+		IconsByExtension: map[string]string{"go": "G"},
+		LSColors:         "di=01;34:ln=01;36:or=40;31:ex=01;32:*.png=01;35:*.tar.gz=01;31:*.gz=31",
+	}}
+	tr, err := tree.New(root, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := tr.Lines()
+	e := map[string]struct{ group, color string }{
+		"a.go":     {tree.GroupFile, ""},
+		"b.png":    {tree.GroupFile, "01;35"},
+		"c.tar.gz": {tree.GroupArchive, "01;31"},
+		"d":        {tree.GroupDirectory, "01;34"},
+		"link":     {tree.GroupSymlink, "01;36"},
+		"orphan":   {tree.GroupOrphan, "40;31"},
+		"run.sh":   {tree.GroupExecutable, "01;32"},
+	}
+	hs := tr.Highlights()
+	if a, e := len(hs), len(e)+1; a != e {
+		t.Fatalf("Highlights() should return a highlight per row, expected %d, but actual %d", e, a)
+	}
+	for _, h := range hs[1:] {
+		name := string(lines[h.Row][h.Start:h.End])
+		ex, ok := e[name]
+		if !ok {
+			t.Errorf("Highlights() should point the name, but points '%s' in '%s'", name, lines[h.Row])
+			continue
+		}
+		if h.Group != ex.group || h.Color != ex.color {
+			t.Errorf("'%s' should be highlighted as %s with '%s', but %s with '%s'", name, ex.group, ex.color, h.Group, h.Color)
+		}
+	}
+	a := linesToString(lines)
+	ex := "\uf07c root/\n" +
+		"+ \uf07b d/\n" +
+		"| G a.go\n" +
+		"| \uf1c5 b.png\n" +
+		"+ \uf410 c.tar.gz/\n" +
+		"| \uf481 link\n" +
+		"| \uf481 orphan\n" +
+		"| \uf489 run.sh"
+	if a != ex {
+		t.Errorf("the icons should be rendered with the ones overridden in Config\nexpected:\n%s\nactual:\n%s", ex, a)
+	}
+}
@@ -1,12 +1,24 @@
 package tree
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 
 	"github.com/skratchdot/open-golang/open"
+	shutil "github.com/termie/go-shutil"
 )
 
+// ErrReadOnly is returned when modifying objects which can't be written,
+// such as virtual directories and objects in archives.
+var ErrReadOnly = errors.New("read-only object")
+
+// ErrNoTrash is returned when removing objects on remote servers to trash box,
+// which is on local disk. Use RemovePermanently to remove them.
+var ErrNoTrash = errors.New("no trash box for remote object")
+
 // A Operator represents objects in file system.
 type Operator interface {
 	Context() *Context
@@ -22,6 +34,22 @@ type Operator interface {
 	ToggleSelected()
 }
 
+// NewOperator creates *Dir or *File according to the object at the path.
+func NewOperator(path string, context *Context) (Operator, error) {
+	return newOperator(path, context, LocalFS)
+}
+
+func newOperator(path string, context *Context, fs FileSystem) (Operator, error) {
+	info, err := fs.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return newDir(path, context, fs)
+	}
+	return newFile(path, context, fs)
+}
+
 // Type returns the type of Operator.
 func Type(o Operator) string {
 	switch o.(type) {
@@ -29,6 +57,8 @@ func Type(o Operator) string {
 		return "directory"
 	case *File:
 		return "file"
+	case *ArchiveFile:
+		return "archive file"
 	default:
 		return "undefined"
 	}
@@ -118,35 +148,56 @@ func ToggleRec(o Operator) error {
 }
 
 // CreateDir makes new directories.
-// When o is *Dir, makes under itself.
+// When o is *Dir except archives, makes under itself.
 // In other cases, makes under the parent of o.
 func CreateDir(o Operator, names ...string) error {
-	switch o := o.(type) {
-	case *Dir:
-		return o.CreateDir(names...)
-	default:
-		return o.Parent().CreateDir(names...)
+	if d, ok := o.(*Dir); ok && !IsArchive(d) {
+		return d.CreateDir(names...)
 	}
+	return o.Parent().CreateDir(names...)
 }
 
 // CreateFile makes new files.
-// When o is *Dir, makes under itself.
+// When o is *Dir except archives, makes under itself.
 // In other cases, makes under the parent of o.
 func CreateFile(o Operator, names ...string) error {
+	if d, ok := o.(*Dir); ok && !IsArchive(d) {
+		return d.CreateFile(names...)
+	}
+	return o.Parent().CreateFile(names...)
+}
+
+// IsReadOnly returns that o can't be renamed, moved or removed.
+func IsReadOnly(o Operator) bool {
 	switch o := o.(type) {
 	case *Dir:
-		return o.CreateFile(names...)
+		return o.Virtual()
+	case *ArchiveFile:
+		return true
 	default:
-		return o.Parent().CreateFile(names...)
+		return false
 	}
 }
 
 // Rename renames o to newName.
 func Rename(o Operator, newName string) error {
-	return os.Rename(o.Path(), filepath.Join(o.Dirname(), newName))
+	if IsReadOnly(o) {
+		return ErrReadOnly
+	}
+	fs := FileSystemOf(o)
+	e := Event{Kind: EventRename, Src: o.Path(), Dst: filepath.Join(o.Dirname(), newName), FileSystem: fs}
+	return o.Context().emit(e, func() error {
+		return fs.Rename(e.Src, e.Dst)
+	})
 }
 
 func IsInTrash(o Operator) bool {
+	if d, ok := o.(*Dir); ok && d.Virtual() {
+		return false
+	}
+	if !IsLocal(o) {
+		return false
+	}
 	return o.Dirname() == o.Context().Config.TrashDirname
 }
 
@@ -161,13 +212,60 @@ func OriginalPath(o Operator) string {
 	return info.OriginalPath
 }
 
+// Size returns the size of o.
+// When o is *Dir, returns the total size of the files under it.
+func Size(o Operator) (int64, error) {
+	var size int64
+	err := filepath.Walk(o.Path(), func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+// displayName returns the name of o shown in the tree.
+// The objects placed under its original location in the view of trash box
+// are shown only with the base name of the original path.
+func displayName(o Operator) string {
+	name := OriginalPath(o)
+	if p := o.Parent(); p != nil && p.Virtual() && IsInTrash(o) {
+		return filepath.Base(name)
+	}
+	if c, ok := comparisonOf(o); ok && o.Parent() != nil {
+		if rel, err := c.Rel(o); err == nil {
+			return c.Difference(rel).Mark() + " " + name
+		}
+	}
+	return name
+}
+
 // Move moves o to under the newDirname.
 func Move(o Operator, newDirname string) error {
-	return os.Rename(o.Path(), filepath.Join(newDirname, o.Name()))
+	if IsReadOnly(o) {
+		return ErrReadOnly
+	}
+	fs := FileSystemOf(o)
+	e := Event{Kind: EventMove, Src: o.Path(), Dst: filepath.Join(newDirname, o.Name()), FileSystem: fs}
+	return o.Context().emit(e, func() error {
+		return fs.Rename(e.Src, e.Dst)
+	})
 }
 
 // Remove move o and any children it contains to trash box.
+// The objects on remote servers can't be moved to trash box on local disk,
+// so returns ErrNoTrash for them.
 func Remove(o Operator) error {
+	if IsReadOnly(o) {
+		return ErrReadOnly
+	}
+	if !IsLocal(o) {
+		return ErrNoTrash
+	}
 	if IsInTrash(o) {
 		return nil
 	}
@@ -176,24 +274,126 @@ func Remove(o Operator) error {
 	if err != nil {
 		return err
 	}
-	tp := filepath.Join(o.Context().Config.TrashDirname, tn)
-	return os.Rename(o.Path(), tp)
+	e := Event{Kind: EventRemove, Src: o.Path(), Dst: filepath.Join(o.Context().Config.TrashDirname, tn), FileSystem: LocalFS}
+	return o.Context().emit(e, func() error {
+		return os.Rename(e.Src, e.Dst)
+	})
 }
 
 // RemovePermanently removes o and any children it contains permanently.
 func RemovePermanently(o Operator) error {
-	return os.RemoveAll(o.Path())
+	if IsReadOnly(o) {
+		return ErrReadOnly
+	}
+	fs := FileSystemOf(o)
+	e := Event{Kind: EventRemove, Src: o.Path(), FileSystem: fs}
+	return o.Context().emit(e, func() error {
+		return fs.RemoveAll(e.Src)
+	})
 }
 
 // Restore move o to the original path from trash box.
 func Restore(o Operator) error {
+	return RestoreTo(o, OriginalPath(o))
+}
+
+// RestoreTo move o to the path from trash box.
+// Missing parent directories of the path are created.
+// When an object already exists at the path, returns an error
+// which can be tested with os.IsExist.
+func RestoreTo(o Operator, path string) error {
+	return restoreTo(o, path, nil)
+}
+
+// restoreTo restores o to the path overwriting the object at the path.
+// The overwritten object is moved to trash box after the before hooks,
+// so that a vetoed restore leaves it.
+func restoreTo(o Operator, path string, overwritten Operator) error {
 	if !IsInTrash(o) {
 		return nil
 	}
-	return os.Rename(o.Path(), OriginalPath(o))
+	if _, err := os.Lstat(path); err == nil && overwritten == nil {
+		return &os.PathError{Op: "restore", Path: path, Err: os.ErrExist}
+	}
+	e := Event{Kind: EventRestore, Src: o.Path(), Dst: path, FileSystem: LocalFS}
+	return o.Context().emit(e, func() error {
+		if overwritten != nil {
+			if err := Remove(overwritten); err != nil {
+				return err
+			}
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0775); err != nil {
+			return err
+		}
+		return os.Rename(e.Src, e.Dst)
+	})
+}
+
+// CopyTo copies o and any children it contains to dstPath on local disk.
+// The objects in archives are extracted.
+func CopyTo(o Operator, dstPath string) error {
+	return CopyToFS(o, LocalFS, dstPath)
+}
+
+// CopyToFS copies o and any children it contains to dstPath on fs.
+func CopyToFS(o Operator, fs FileSystem, dstPath string) error {
+	if a, ok := o.(*ArchiveFile); ok && fs == LocalFS {
+		return a.CopyTo(dstPath)
+	}
+	d, isDir := o.(*Dir)
+	if isDir && !d.Virtual() && IsLocal(o) && fs == LocalFS {
+		if o.IsDir() {
+			return shutil.CopyTree(o.Path(), dstPath, nil)
+		}
+		return shutil.CopyFile(o.Path(), dstPath, true)
+	}
+	if isDir && o.IsDir() {
+		if err := fs.MkdirAll(dstPath); err != nil {
+			return err
+		}
+		cs, err := d.read()
+		if err != nil {
+			return err
+		}
+		for _, c := range cs {
+			if err := CopyToFS(c, fs, filepath.Join(dstPath, c.Name())); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if IsLocal(o) && fs == LocalFS {
+		return shutil.CopyFile(o.Path(), dstPath, true)
+	}
+
+	r, err := openContent(o)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	w, err := fs.Create(dstPath)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// openContent opens the content of the file o wherever it exists.
+func openContent(o Operator) (io.ReadCloser, error) {
+	if a, ok := o.(*ArchiveFile); ok {
+		return a.Open()
+	}
+	return FileSystemOf(o).Open(o.Path())
 }
 
 // OpenWithOS opens o with the default application related in OS.
 func OpenWithOS(o Operator) error {
+	if !IsLocal(o) {
+		return fmt.Errorf("'%s' isn't on local disk", o.Path())
+	}
 	return open.Run(o.Path())
 }
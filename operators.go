@@ -1,6 +1,11 @@
 package tree
 
-import "github.com/mattn/natural"
+import (
+	"os"
+	"sort"
+
+	"github.com/mattn/natural"
+)
 
 type Operators []Operator
 
@@ -49,6 +54,15 @@ func (os Operators) FindDir(d *Dir) *Dir {
 	return nil
 }
 
+func (os Operators) Find(o Operator) Operator {
+	for _, t := range os {
+		if Type(t) == Type(o) && Equals(t, o) {
+			return t
+		}
+	}
+	return nil
+}
+
 func (os Operators) FindFile(f *File) *File {
 	for _, o := range os {
 		t, ok := o.(*File)
@@ -61,3 +75,121 @@ func (os Operators) FindFile(f *File) *File {
 	}
 	return nil
 }
+
+// Expand replaces virtual directories with the objects under them.
+// The objects appearing more than once are included only once.
+func (os Operators) Expand() (Operators, error) {
+	expanded := Operators{}
+	found := map[string]bool{}
+	var expand func(Operators) error
+	expand = func(os Operators) error {
+		for _, o := range os {
+			if d, ok := o.(*Dir); ok && d.Virtual() {
+				cs, err := d.list()
+				if err != nil {
+					return err
+				}
+				if err := expand(cs); err != nil {
+					return err
+				}
+				continue
+			}
+			if found[o.Path()] {
+				continue
+			}
+			found[o.Path()] = true
+			expanded = append(expanded, o)
+		}
+		return nil
+	}
+	if err := expand(os); err != nil {
+		return nil, err
+	}
+	return expanded, nil
+}
+
+// The orders of the objects in a directory.
+// Directories are always placed before files.
+const (
+	SortName    = "name"
+	SortSize    = "size"
+	SortModTime = "mtime"
+)
+
+// browsesArchives returns that archives are browsed as directories.
+// A nil Config browses them.
+func (c *Config) browsesArchives() bool {
+	return c == nil || !c.ArchivesAsFiles
+}
+
+// sort sorts os in the order of SortBy and SortReverse.
+// A nil Config sorts os by name.
+func (c *Config) sort(os Operators) {
+	if c == nil {
+		sort.Sort(os)
+		return
+	}
+	var s sort.Interface = os
+	switch c.SortBy {
+	case SortSize:
+		s = bySize{os}
+	case SortModTime:
+		s = byModTime{os}
+	}
+	if c.SortReverse {
+		s = dirsFirst{os, sort.Reverse(s)}
+	}
+	sort.Stable(s)
+}
+
+type bySize struct {
+	Operators
+}
+
+func (os bySize) Less(i, j int) bool {
+	if less, ok := os.Operators.dirsFirst(i, j); ok {
+		return less
+	}
+	return infoOf(os.Operators[i]).Size() < infoOf(os.Operators[j]).Size()
+}
+
+type byModTime struct {
+	Operators
+}
+
+func (os byModTime) Less(i, j int) bool {
+	if less, ok := os.Operators.dirsFirst(i, j); ok {
+		return less
+	}
+	return infoOf(os.Operators[i]).ModTime().Before(infoOf(os.Operators[j]).ModTime())
+}
+
+// dirsFirst keeps directories before files when the order of s is reversed.
+type dirsFirst struct {
+	Operators
+	s sort.Interface
+}
+
+func (os dirsFirst) Less(i, j int) bool {
+	if less, ok := os.Operators.dirsFirst(i, j); ok {
+		return less
+	}
+	return os.s.Less(i, j)
+}
+
+// dirsFirst returns the order of i-th and j-th objects
+// when one is a directory and the other isn't.
+func (os Operators) dirsFirst(i, j int) (bool, bool) {
+	aIsDir, bIsDir := os[i].IsDir(), os[j].IsDir()
+	if aIsDir == bIsDir {
+		return false, false
+	}
+	return aIsDir, true
+}
+
+func infoOf(o Operator) os.FileInfo {
+	if info, ok := o.(os.FileInfo); ok {
+		return info
+	}
+	return virtualInfo{name: o.Name()}
+}
@@ -0,0 +1,242 @@
+package tree
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// The kinds of Preview.
+const (
+	PreviewText      = "text"
+	PreviewBinary    = "binary"
+	PreviewImage     = "image"
+	PreviewDirectory = "directory"
+)
+
+// A Preview is the summary of an object shown in a side pane.
+type Preview struct {
+	Kind    string
+	Path    string
+	Size    int64
+	Mode    os.FileMode
+	ModTime time.Time
+
+	// Encoding and Lines are the detected encoding
+	// and the first lines of a text file.
+	Encoding string
+	Lines    []string
+
+	// Hex is the hex dump of the head of a binary file.
+	Hex string
+
+	// Format, Width and Height describe an image.
+	Format string
+	Width  int
+	Height int
+
+	// Children and TotalSize are the number of the children
+	// and the total size of the files under a directory.
+	// Partial is true when TotalSize doesn't count all the files,
+	// because some of them are unreadable or too many.
+	Children  int
+	TotalSize int64
+	Partial   bool
+}
+
+// NewPreview creates the preview of o.
+// Text files are previewed with the first lines lines,
+// and binary files with the hex dump of the first size bytes.
+// The total size of a directory counts the first files files under it.
+func NewPreview(o Operator, lines int, size int, files int) (Preview, error) {
+	p := Preview{Path: o.Path()}
+	if info, ok := o.(os.FileInfo); ok {
+		p.Size = info.Size()
+		p.Mode = info.Mode()
+		p.ModTime = info.ModTime()
+	}
+	if d, ok := o.(*Dir); ok {
+		p.Kind = PreviewDirectory
+		cs, err := d.read()
+		if err != nil {
+			p.Partial = true
+			return p, nil
+		}
+		p.Children = len(cs)
+		p.TotalSize, p.Partial = dirSize(d, files)
+		return p, nil
+	}
+
+	r, err := openContent(o)
+	if err != nil {
+		return p, err
+	}
+	defer r.Close()
+	// Read enough bytes to detect the kind and decode the first lines,
+	// but never the whole of a large file.
+	head, err := ioutil.ReadAll(io.LimitReader(r, int64(lines*1024+size)))
+	if err != nil {
+		return p, err
+	}
+
+	if c, format, err := image.DecodeConfig(bytes.NewReader(head)); err == nil {
+		p.Kind = PreviewImage
+		p.Format = format
+		p.Width = c.Width
+		p.Height = c.Height
+		return p, nil
+	}
+	if enc, text, ok := decodeText(head); ok {
+		p.Kind = PreviewText
+		p.Encoding = enc
+		p.Lines = headLines(text, lines)
+		return p, nil
+	}
+	p.Kind = PreviewBinary
+	if len(head) > size {
+		head = head[:size]
+	}
+	p.Hex = hex.Dump(head)
+	return p, nil
+}
+
+// decodeText detects the encoding of b and decodes it.
+// Returns false when b seems to be binary.
+func decodeText(b []byte) (string, string, bool) {
+	switch {
+	case bytes.HasPrefix(b, []byte{0xef, 0xbb, 0xbf}):
+		return "utf-8", string(b[3:]), true
+	case bytes.HasPrefix(b, []byte{0xff, 0xfe}):
+		return "utf-16le", decodeUTF16(b[2:], false), true
+	case bytes.HasPrefix(b, []byte{0xfe, 0xff}):
+		return "utf-16be", decodeUTF16(b[2:], true), true
+	}
+	if bytes.IndexByte(b, 0) != -1 {
+		return "", "", false
+	}
+	if validUTF8(b) {
+		return "utf-8", string(b), true
+	}
+	controls := 0
+	for _, c := range b {
+		if c < 0x20 && c != '\n' && c != '\r' && c != '\t' && c != '\f' {
+			controls++
+		}
+	}
+	if controls*10 > len(b) {
+		return "", "", false
+	}
+	rs := make([]rune, len(b))
+	for i, c := range b {
+		rs[i] = rune(c)
+	}
+	return "iso-8859-1", string(rs), true
+}
+
+// validUTF8 returns that b is valid UTF-8,
+// allowing the rune cut at the end of b.
+func validUTF8(b []byte) bool {
+	for len(b) > 0 {
+		r, n := utf8.DecodeRune(b)
+		if r == utf8.RuneError && n <= 1 {
+			return len(b) < utf8.UTFMax && !utf8.FullRune(b)
+		}
+		b = b[n:]
+	}
+	return true
+}
+
+func decodeUTF16(b []byte, bigEndian bool) string {
+	us := make([]uint16, len(b)/2)
+	for i := range us {
+		if bigEndian {
+			us[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+		} else {
+			us[i] = uint16(b[2*i+1])<<8 | uint16(b[2*i])
+		}
+	}
+	return string(utf16.Decode(us))
+}
+
+func headLines(text string, n int) []string {
+	lines := []string{}
+	s := bufio.NewScanner(strings.NewReader(text))
+	for len(lines) < n && s.Scan() {
+		lines = append(lines, s.Text())
+	}
+	return lines
+}
+
+// errTooManyFiles stops walking the directory in dirSize.
+var errTooManyFiles = errors.New("too many files")
+
+// dirSize returns the total size of the first max files under d.
+// The unreadable descendants are skipped,
+// and partial is true when any file isn't counted.
+func dirSize(d *Dir, max int) (size int64, partial bool) {
+	files := 0
+	count := func(info os.FileInfo) error {
+		if files >= max {
+			partial = true
+			return errTooManyFiles
+		}
+		files++
+		size += info.Size()
+		return nil
+	}
+
+	if IsLocal(d) && d.list == nil {
+		filepath.Walk(d.Path(), func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				partial = true
+				if info != nil && info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if info.IsDir() {
+				return nil
+			}
+			return count(info)
+		})
+		return size, partial
+	}
+
+	var walk func(d *Dir) error
+	walk = func(d *Dir) error {
+		cs, err := d.read()
+		if err != nil {
+			partial = true
+			return nil
+		}
+		for _, c := range cs {
+			if c, ok := c.(*Dir); ok {
+				if err := walk(c); err != nil {
+					return err
+				}
+				continue
+			}
+			if info, ok := c.(os.FileInfo); ok {
+				if err := count(info); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	walk(d)
+	return size, partial
+}
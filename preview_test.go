@@ -0,0 +1,98 @@
+package tree_test
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tree "github.com/minodisk/go-tree"
+)
+
+func TestPreview(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-tree-preview")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 3, 2))); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{
+		"d/text.txt":   []byte("first\nsecond\nthird\n"),
+		"d/latin1.txt": []byte("caf\xe9\n"),
+		"d/utf16.txt":  {0xff, 0xfe, 'h', 0, 'i', 0},
+		"d/binary":     {0x7f, 'E', 'L', 'F', 0, 1, 2},
+		"d/image.png":  img.Bytes(),
+	}
+	var size int64
+	for name, content := range files {
+		size += int64(len(content))
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0775); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, content, 0664); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := &tree.Context{Config: &tree.Config{}}
+	if err := c.Init(); err != nil {
+		t.Fatal(err)
+	}
+	previewFiles := func(name string, files int) tree.Preview {
+		o, err := tree.NewOperator(filepath.Join(dir, name), c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, err := tree.NewPreview(o, 2, 4, files)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	preview := func(name string) tree.Preview {
+		return previewFiles(name, 10)
+	}
+
+	p := preview("d/text.txt")
+	if p.Kind != tree.PreviewText || p.Encoding != "utf-8" || strings.Join(p.Lines, ",") != "first,second" {
+		t.Errorf("NewPreview() should return the first lines of the text, but returns %+v", p)
+	}
+	if p := preview("d/latin1.txt"); p.Encoding != "iso-8859-1" || p.Lines[0] != "café" {
+		t.Errorf("NewPreview() should decode Latin-1, but returns %+v", p)
+	}
+	if p := preview("d/utf16.txt"); p.Encoding != "utf-16le" || p.Lines[0] != "hi" {
+		t.Errorf("NewPreview() should decode UTF-16 with BOM, but returns %+v", p)
+	}
+	if p := preview("d/binary"); p.Kind != tree.PreviewBinary || !strings.HasPrefix(p.Hex, "00000000  7f 45 4c 46  ") {
+		t.Errorf("NewPreview() should dump the head of the binary, but returns %+v", p)
+	}
+	if p := preview("d/image.png"); p.Kind != tree.PreviewImage || p.Format != "png" || p.Width != 3 || p.Height != 2 {
+		t.Errorf("NewPreview() should return the dimensions of the image, but returns %+v", p)
+	}
+
+	if p := preview("d"); p.Children != 5 || p.TotalSize != size || p.Partial {
+		t.Errorf("NewPreview() should count all the files under the directory, but returns %+v", p)
+	}
+	if p := previewFiles("d", 2); p.Children != 5 || p.TotalSize == size || !p.Partial {
+		t.Errorf("NewPreview() should count the limited number of the files as partial, but returns %+v", p)
+	}
+
+	tr, clean := newTree(t, dir)
+	defer clean()
+	if err := tr.Preview(cursorAt(1), func(p tree.Preview) error {
+		if p.Kind != tree.PreviewDirectory || p.Children != 5 || p.TotalSize != size {
+			t.Errorf("Preview() should summarize the directory, but passes %+v", p)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
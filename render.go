@@ -0,0 +1,24 @@
+package tree
+
+// The styles to render the tree.
+const (
+	// RenderIndent indents the rows with Config.Indent.
+	RenderIndent = "indent"
+	// RenderBox draws the guides with box-drawing characters like the tree command.
+	RenderBox = "box"
+	// RenderASCII draws the guides with ASCII characters.
+	RenderASCII = "ascii"
+)
+
+// A guide is the set of the strings drawn before the rows.
+type guide struct {
+	branch   string
+	last     string
+	vertical string
+	space    string
+}
+
+var guides = map[string]guide{
+	RenderBox:   {branch: "├── ", last: "└── ", vertical: "│   ", space: "    "},
+	RenderASCII: {branch: "|-- ", last: "`-- ", vertical: "|   ", space: "    "},
+}
@@ -0,0 +1,74 @@
+package tree_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	tree "github.com/minodisk/go-tree"
+)
+
+func TestRenderStyle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-tree-render")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	root := filepath.Join(dir, "root")
+	for _, d := range []string{"a/aa", "b"} {
+		if err := os.MkdirAll(filepath.Join(root, d), 0775); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, f := range []string{"a/x.txt", "a/y.txt", "z.txt"} {
+		if err := ioutil.WriteFile(filepath.Join(root, f), []byte{}, 0664); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, c := range []struct {
+		style string
+		e     string
+	}{
+		{tree.RenderBox, `root/
+├── - a/
+│   ├── + aa/
+│   ├── x.txt
+│   └── * y.txt
+├── + b/
+└── z.txt`},
+		{tree.RenderASCII, `root/
+|-- - a/
+|   |-- + aa/
+|   |-- x.txt
+|   ` + "`" + `-- * y.txt
+|-- + b/
+` + "`" + `-- z.txt`},
+	} {
+		tr, err := tree.New(root, &tree.Context{Config: &tree.Config{
+			TrashDirname:   filepath.Join(dir, "trash"),
+			VisitsFilename: filepath.Join(dir, "visits.json"),
+			RenderStyle:    c.style,
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := tr.Toggle(cursorAt(1), noRender); err != nil {
+			t.Fatal(err)
+		}
+		if err := tr.Select(cursorAt(4), func(int) error { return nil }, noRender); err != nil {
+			t.Fatal(err)
+		}
+		if a := linesToString(tr.Lines()); a != c.e {
+			t.Errorf("%s style should draw the guides\nexpected:\n%s\nactual:\n%s", c.style, c.e, a)
+		}
+		if o, ok := tr.IndexOf(4); !ok || o.Name() != "y.txt" {
+			t.Errorf("%s style should keep a row per object", c.style)
+		}
+	}
+
+	if _, err := tree.New(root, &tree.Context{Config: &tree.Config{RenderStyle: "unknown"}}); err == nil {
+		t.Errorf("New() should fail with the unknown render style")
+	}
+}
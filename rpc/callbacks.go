@@ -0,0 +1,92 @@
+package rpc
+
+import (
+	tree "github.com/minodisk/go-tree"
+)
+
+// client implements tree.UI with the requests and the notifications to the client.
+type client struct {
+	s *Server
+}
+
+func (c client) Cursor() (int, error) {
+	var n int
+	err := c.s.call("cursor", nil, &n)
+	return n, err
+}
+
+func (c client) SetCursor(n int) error {
+	return c.s.notify("setCursor", []interface{}{n})
+}
+
+func (c client) Render(lines [][]byte) error {
+	return c.s.notify("render", []interface{}{stringsOf(lines)})
+}
+
+// Confirm sends the paths of the objects.
+func (c client) Confirm(os ...tree.Operator) (bool, error) {
+	ps := make([]string, len(os))
+	for i, o := range os {
+		ps[i] = o.Path()
+	}
+	var ok bool
+	err := c.s.call("confirm", []interface{}{ps}, &ok)
+	return ok, err
+}
+
+// Cancel notifies the client, and the command finishes without an error.
+func (c client) Cancel() error {
+	return c.s.notify("cancel", nil)
+}
+
+func (c client) OpenFile(f *tree.File) error {
+	return c.s.notify("openFile", []interface{}{f.Path()})
+}
+
+func (c client) SetClipboard(text string) error {
+	return c.s.notify("setClipboard", []interface{}{text})
+}
+
+// Input returns ErrCanceled when the client responds null.
+func (c client) Input(prompt, value string) (string, error) {
+	var t *string
+	if err := c.s.call("input", []interface{}{prompt, value}, &t); err != nil {
+		return "", err
+	}
+	if t == nil {
+		return "", ErrCanceled
+	}
+	return *t, nil
+}
+
+// Inputs returns ErrCanceled when the client responds null.
+func (c client) Inputs(prompt string, values []string) ([]string, error) {
+	var ts *[]string
+	if err := c.s.call("inputs", []interface{}{prompt, values}, &ts); err != nil {
+		return nil, err
+	}
+	if ts == nil {
+		return nil, ErrCanceled
+	}
+	return *ts, nil
+}
+
+// Choose returns ErrCanceled when the client responds null.
+func (c client) Choose(prompt string, choices []string) (string, error) {
+	var ch *string
+	if err := c.s.call("choose", []interface{}{prompt, choices}, &ch); err != nil {
+		return "", err
+	}
+	if ch == nil {
+		return "", ErrCanceled
+	}
+	return *ch, nil
+}
+
+func stringsOf(lines [][]byte) []string {
+	ls := make([]string, len(lines))
+	for i, l := range lines {
+		ls[i] = string(l)
+	}
+	return ls
+}
@@ -0,0 +1,116 @@
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+
+	tree "github.com/minodisk/go-tree"
+)
+
+// params is the params of the request.
+type params json.RawMessage
+
+// decode decodes the params into v.
+// The missing params leave v as it is.
+func (p params) decode(v interface{}) error {
+	if len(p) == 0 || string(p) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(p, v); err != nil {
+		return &Error{Code: CodeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+// A command takes the params of the request
+// and returns the result of the response.
+type command func(s *Server, p params) (interface{}, error)
+
+// commands are the commands which take the params or return the data.
+// They precede the commands of Tree with the same names,
+// and the other requests are dispatched to Tree by name.
+var commands = map[string]command{
+	"commands": func(s *Server, p params) (interface{}, error) {
+		cs := s.tree.Commands()
+		infos := []commandInfo{}
+		for _, name := range cs.Names() {
+			c, _ := cs.Lookup(name)
+			infos = append(infos, commandInfo{c.Name, c.Description, c.Mutates, c.UsesSelection, c.NeedsSelection})
+		}
+		return infos, nil
+	},
+	"lines": func(s *Server, p params) (interface{}, error) {
+		return stringsOf(s.tree.Lines()), nil
+	},
+	"reveal": func(s *Server, p params) (interface{}, error) {
+		var ps struct {
+			Path   string `json:"path"`
+			Policy string `json:"policy"`
+		}
+		if err := p.decode(&ps); err != nil {
+			return nil, err
+		}
+		policy, ok := revealPolicies[ps.Policy]
+		if !ok {
+			return nil, &Error{Code: CodeInvalidParams, Message: fmt.Sprintf("unknown policy '%s'", ps.Policy)}
+		}
+		return s.tree.Reveal(ps.Path, policy, client{s}.Render)
+	},
+	"selectRange": func(s *Server, p params) (interface{}, error) {
+		var r tree.Range
+		if err := p.decode(&r); err != nil {
+			return nil, err
+		}
+		return s.tree.SelectRange(func() (tree.Range, error) { return r, nil }, client{s}.Render)
+	},
+	"selectGlob": func(s *Server, p params) (interface{}, error) {
+		scope, err := selectScope(p)
+		if err != nil {
+			return nil, err
+		}
+		c := client{s}
+		return s.tree.SelectGlob(c.Cursor, func() (string, error) { return c.Input("glob: ", "") }, scope, c.Render)
+	},
+	"selectRegexp": func(s *Server, p params) (interface{}, error) {
+		scope, err := selectScope(p)
+		if err != nil {
+			return nil, err
+		}
+		c := client{s}
+		return s.tree.SelectRegexp(c.Cursor, func() (string, error) { return c.Input("regexp: ", "") }, scope, c.Render)
+	},
+}
+
+// commandInfo is the metadata of a command returned by "commands".
+type commandInfo struct {
+	Name           string `json:"name"`
+	Description    string `json:"description"`
+	Mutates        bool   `json:"mutates"`
+	UsesSelection  bool   `json:"usesSelection"`
+	NeedsSelection bool   `json:"needsSelection"`
+}
+
+var revealPolicies = map[string]tree.RevealPolicy{
+	"":        tree.RevealFail,
+	"fail":    tree.RevealFail,
+	"parent":  tree.RevealParent,
+	"project": tree.RevealProject,
+	"append":  tree.RevealAppend,
+}
+
+// selectScope decodes the scope like {"scope": "descendants"}.
+func selectScope(p params) (tree.SelectScope, error) {
+	var ps struct {
+		Scope string `json:"scope"`
+	}
+	if err := p.decode(&ps); err != nil {
+		return 0, err
+	}
+	switch ps.Scope {
+	case "", "shown":
+		return tree.SelectShown, nil
+	case "descendants":
+		return tree.SelectDescendants, nil
+	}
+	return 0, &Error{Code: CodeInvalidParams, Message: fmt.Sprintf("unknown scope '%s'", ps.Scope)}
+}
@@ -0,0 +1,298 @@
+// Package rpc serves the commands of Tree over JSON-RPC 2.0,
+// so that any editor can drive the tree with a thin client.
+//
+// The messages are JSON objects delimited with newlines.
+// The client requests a command registered in Tree by the name like "rename",
+// and the server calls the callbacks of the command back to the client.
+// "commands" returns the names and the metadata of the commands.
+// The callbacks are:
+//
+//	cursor                            -> the row of the cursor
+//	setCursor   [row]                 (notification)
+//	render      [lines]               (notification)
+//	confirm     [paths]               -> true to continue
+//	input       [prompt, default]     -> the text, or null to cancel
+//	inputs      [prompt, defaults]    -> the texts, or null to cancel
+//	choose      [prompt, choices]     -> one of the choices, or null to cancel
+//	cancel                            (notification)
+//	openFile    [path]                (notification)
+//	setClipboard [text]               (notification)
+package rpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	tree "github.com/minodisk/go-tree"
+)
+
+// The error codes of JSON-RPC 2.0.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeCommandFailed  = -32000
+)
+
+// ErrCanceled is returned by the callbacks when the client cancels the input.
+var ErrCanceled = errors.New("canceled")
+
+// An Error is the error object of JSON-RPC 2.0.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (%d)", e.Message, e.Code)
+}
+
+// message is a request, a notification or a response.
+type message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *Error           `json:"error,omitempty"`
+}
+
+type request struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      *int64      `json:"id,omitempty"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+type resultResponse struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Result  interface{}      `json:"result"`
+}
+
+type errorResponse struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Error   *Error           `json:"error"`
+}
+
+// A Server runs the commands requested by the client one by one.
+type Server struct {
+	tree *tree.Tree
+	dec  *json.Decoder
+
+	wmu sync.Mutex
+	enc *json.Encoder
+
+	pmu     sync.Mutex
+	pending map[int64]chan *message
+	lastID  int64
+	closed  bool
+
+	queue *queue
+}
+
+// NewServer creates a server reading the messages from r and writing to w.
+func NewServer(t *tree.Tree, r io.Reader, w io.Writer) *Server {
+	return &Server{
+		tree:    t,
+		dec:     json.NewDecoder(r),
+		enc:     json.NewEncoder(w),
+		pending: map[int64]chan *message{},
+		queue:   newQueue(),
+	}
+}
+
+// Serve serves until r reaches EOF.
+// The requests are queued, since the responses to the callbacks
+// have to be read while a command is running.
+func (s *Server) Serve() error {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			m, ok := s.queue.pop()
+			if !ok {
+				return
+			}
+			s.handle(m)
+		}
+	}()
+
+	err := s.read()
+	s.queue.close()
+	s.closePending()
+	<-done
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+func (s *Server) read() error {
+	for {
+		var raw json.RawMessage
+		if err := s.dec.Decode(&raw); err != nil {
+			if _, ok := err.(*json.SyntaxError); ok {
+				s.respondError(nil, CodeParseError, err.Error())
+			}
+			return err
+		}
+		m := &message{}
+		if err := json.Unmarshal(raw, m); err != nil {
+			s.respondError(nil, CodeInvalidRequest, err.Error())
+			continue
+		}
+		if m.Method != "" {
+			s.queue.push(m)
+			continue
+		}
+		s.resolve(m)
+	}
+}
+
+// handle runs the command and responds to the request.
+// No response is written to the notification.
+func (s *Server) handle(m *message) {
+	c, ok := commands[m.Method]
+	if !ok {
+		if _, ok := s.tree.Commands().Lookup(m.Method); !ok {
+			if m.ID != nil {
+				s.respondError(m.ID, CodeMethodNotFound, fmt.Sprintf("unknown command '%s'", m.Method))
+			}
+			return
+		}
+		c = func(s *Server, p params) (interface{}, error) {
+			return s.tree.Dispatch(m.Method, client{s})
+		}
+	}
+	result, err := c(s, params(m.Params))
+	if m.ID == nil {
+		return
+	}
+	switch err := err.(type) {
+	case nil:
+		s.write(resultResponse{JSONRPC: "2.0", ID: m.ID, Result: result})
+	case *Error:
+		s.respondError(m.ID, err.Code, err.Message)
+	default:
+		s.respondError(m.ID, CodeCommandFailed, err.Error())
+	}
+}
+
+func (s *Server) respondError(id *json.RawMessage, code int, msg string) {
+	s.write(errorResponse{JSONRPC: "2.0", ID: id, Error: &Error{Code: code, Message: msg}})
+}
+
+func (s *Server) write(v interface{}) error {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	return s.enc.Encode(v)
+}
+
+// call requests the method to the client and waits for the response.
+func (s *Server) call(method string, params interface{}, result interface{}) error {
+	s.pmu.Lock()
+	if s.closed {
+		s.pmu.Unlock()
+		return io.ErrUnexpectedEOF
+	}
+	s.lastID++
+	id := s.lastID
+	ch := make(chan *message, 1)
+	s.pending[id] = ch
+	s.pmu.Unlock()
+
+	if err := s.write(request{JSONRPC: "2.0", ID: &id, Method: method, Params: params}); err != nil {
+		return err
+	}
+	m, ok := <-ch
+	if !ok {
+		return io.ErrUnexpectedEOF
+	}
+	if m.Error != nil {
+		return m.Error
+	}
+	return json.Unmarshal(m.Result, result)
+}
+
+// notify sends the method to the client without waiting.
+func (s *Server) notify(method string, params interface{}) error {
+	return s.write(request{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+// resolve passes the response to the waiting call.
+func (s *Server) resolve(m *message) {
+	if m.ID == nil {
+		return
+	}
+	var id int64
+	if err := json.Unmarshal(*m.ID, &id); err != nil {
+		return
+	}
+	s.pmu.Lock()
+	ch, ok := s.pending[id]
+	delete(s.pending, id)
+	s.pmu.Unlock()
+	if ok {
+		ch <- m
+	}
+}
+
+func (s *Server) closePending() {
+	s.pmu.Lock()
+	defer s.pmu.Unlock()
+	s.closed = true
+	for id, ch := range s.pending {
+		close(ch)
+		delete(s.pending, id)
+	}
+}
+
+// queue is the unbounded queue of the requests.
+type queue struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	ms     []*message
+	closed bool
+}
+
+func newQueue() *queue {
+	q := &queue{}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+func (q *queue) push(m *message) {
+	q.mu.Lock()
+	q.ms = append(q.ms, m)
+	q.mu.Unlock()
+	q.cond.Signal()
+}
+
+// pop returns the oldest message, or false after closed.
+// The messages queued before closed are still returned.
+func (q *queue) pop() (*message, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.ms) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	if len(q.ms) == 0 {
+		return nil, false
+	}
+	m := q.ms[0]
+	q.ms = q.ms[1:]
+	return m, true
+}
+
+func (q *queue) close() {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+	q.cond.Broadcast()
+}
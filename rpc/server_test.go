@@ -0,0 +1,180 @@
+package rpc_test
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	tree "github.com/minodisk/go-tree"
+	"github.com/minodisk/go-tree/rpc"
+)
+
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int            `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpc.Error      `json:"error,omitempty"`
+}
+
+// client answers the callbacks with the responses for the methods
+// and returns the responses to the requests by the ids.
+type client struct {
+	enc       *json.Encoder
+	dec       *json.Decoder
+	responses map[string]interface{}
+	rendered  []string
+	calls     []string
+}
+
+func (c *client) request(t *testing.T, id int, method string, params interface{}) message {
+	req := map[string]interface{}{"jsonrpc": "2.0", "id": id, "method": method}
+	if params != nil {
+		req["params"] = params
+	}
+	if err := c.enc.Encode(req); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		var m message
+		if err := c.dec.Decode(&m); err != nil {
+			t.Fatal(err)
+		}
+		if m.Method == "" {
+			if m.ID == nil || *m.ID != id {
+				t.Fatalf("the response should have the id %d, but %v", id, m.ID)
+			}
+			return m
+		}
+		c.calls = append(c.calls, m.Method)
+		if m.Method == "render" {
+			var ps [][]string
+			if err := json.Unmarshal(m.Params, &ps); err != nil {
+				t.Fatal(err)
+			}
+			c.rendered = ps[0]
+		}
+		if m.ID == nil {
+			continue
+		}
+		if err := c.enc.Encode(map[string]interface{}{"jsonrpc": "2.0", "id": *m.ID, "result": c.responses[m.Method]}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestServer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-tree-rpc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	root := filepath.Join(dir, "root")
+	if err := os.MkdirAll(root, 0775); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, "a.txt"), []byte{}, 0664); err != nil {
+		t.Fatal(err)
+	}
+	tr, err := tree.New(root, &tree.Context{Config: &tree.Config{
+		TrashDirname:   filepath.Join(dir, "trash"),
+		VisitsFilename: filepath.Join(dir, "visits.json"),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sr, cw := io.Pipe()
+	cr, sw := io.Pipe()
+	done := make(chan error)
+	go func() {
+		done <- rpc.NewServer(tr, sr, sw).Serve()
+	}()
+	c := &client{enc: json.NewEncoder(cw), dec: json.NewDecoder(cr), responses: map[string]interface{}{
+		"cursor":  0,
+		"inputs":  []string{"b.txt", "c.txt"},
+		"confirm": true,
+	}}
+
+	if m := c.request(t, 1, "createFile", nil); m.Error != nil {
+		t.Fatalf("createFile should succeed, but %s", m.Error)
+	}
+	if a, e := c.rendered, []string{"root/", "| a.txt", "| b.txt", "| c.txt"}; !equal(a, e) {
+		t.Errorf("the tree should be rendered to the client\nexpected: %v\nactual: %v", e, a)
+	}
+	if a, e := c.calls, []string{"cursor", "cursor", "inputs", "render", "setCursor"}; !equal(a, e) {
+		t.Errorf("the callbacks should be called to the client\nexpected: %v\nactual: %v", e, a)
+	}
+
+	c.responses["cursor"] = 1
+	c.responses["input"] = nil
+	if m := c.request(t, 2, "rename", nil); m.Error == nil || m.Error.Message != rpc.ErrCanceled.Error() {
+		t.Errorf("the canceled input should fail the command, but %v", m.Error)
+	}
+	if _, err := os.Stat(filepath.Join(root, "a.txt")); err != nil {
+		t.Errorf("the canceled command shouldn't rename the file")
+	}
+
+	if m := c.request(t, 3, "remove", nil); m.Error != nil {
+		t.Fatalf("remove should succeed, but %s", m.Error)
+	}
+	var lines []string
+	m := c.request(t, 4, "lines", nil)
+	if err := json.Unmarshal(m.Result, &lines); err != nil {
+		t.Fatal(err)
+	}
+	if e := []string{"root/", "| b.txt", "| c.txt"}; !equal(lines, e) {
+		t.Errorf("lines should return the lines\nexpected: %v\nactual: %v", e, lines)
+	}
+
+	if m := c.request(t, 5, "unknown", nil); m.Error == nil || m.Error.Code != rpc.CodeMethodNotFound {
+		t.Errorf("the unknown command should fail with %d, but %v", rpc.CodeMethodNotFound, m.Error)
+	}
+	if m := c.request(t, 6, "reveal", map[string]string{"policy": "nowhere"}); m.Error == nil || m.Error.Code != rpc.CodeInvalidParams {
+		t.Errorf("the invalid params should fail with %d, but %v", rpc.CodeInvalidParams, m.Error)
+	}
+
+	var selected int
+	if err := json.Unmarshal(c.request(t, 7, "selectRange", map[string]int{"Start": 1, "End": 2}).Result, &selected); err != nil {
+		t.Fatal(err)
+	}
+	if selected != 2 {
+		t.Errorf("selectRange should select the objects in the range of the params, but %d", selected)
+	}
+
+	var infos []struct {
+		Name    string `json:"name"`
+		Mutates bool   `json:"mutates"`
+	}
+	if err := json.Unmarshal(c.request(t, 8, "commands", nil).Result, &infos); err != nil {
+		t.Fatal(err)
+	}
+	mutates := map[string]bool{}
+	for _, i := range infos {
+		mutates[i.Name] = i.Mutates
+	}
+	if m, ok := mutates["rename"]; !ok || !m {
+		t.Errorf("commands should return the metadata of the commands, but %v", infos)
+	}
+
+	cw.Close()
+	if err := <-done; err != nil {
+		t.Errorf("Serve() should finish at EOF, but %s", err)
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,79 @@
+package tree
+
+import "sort"
+
+// positionOf returns the position of o to key the selection.
+func positionOf(o Operator) position {
+	return position{FileSystemOf(o), o.Path()}
+}
+
+func (c *Context) isSelected(o Operator) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.selection[positionOf(o)]
+	return ok
+}
+
+func (c *Context) selectOperator(o Operator) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.selection == nil {
+		c.selection = map[position]Operator{}
+	}
+	c.selection[positionOf(o)] = o
+}
+
+func (c *Context) unselectOperator(o Operator) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.selection, positionOf(o))
+}
+
+// hasSelected returns that any object is selected
+// without checking that the selected objects still exist.
+func (c *Context) hasSelected() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.selection) > 0
+}
+
+// selecteds returns the selected objects ordered by the path.
+func (c *Context) selecteds() Operators {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	os := Operators{}
+	for _, o := range c.selection {
+		os = append(os, o)
+	}
+	sort.Sort(byPath(os))
+	return os
+}
+
+type byPath Operators
+
+func (os byPath) Len() int {
+	return len(os)
+}
+
+func (os byPath) Swap(i, j int) {
+	os[i], os[j] = os[j], os[i]
+}
+
+func (os byPath) Less(i, j int) bool {
+	return os[i].Path() < os[j].Path()
+}
+
+// exists returns that the object of o still exists.
+// Virtual directories and files in archives are assumed to exist.
+func exists(o Operator) bool {
+	switch o := o.(type) {
+	case *Dir:
+		if o.Virtual() {
+			return true
+		}
+	case *ArchiveFile:
+		return true
+	}
+	_, err := FileSystemOf(o).Lstat(o.Path())
+	return err == nil
+}
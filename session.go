@@ -0,0 +1,69 @@
+package tree
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+)
+
+// A Session is the state of a Tree to rebuild it later.
+// The Filter of the Context is a function and can't be serialized,
+// so it isn't recorded and should be set again by the application.
+type Session struct {
+	Roots       []SessionRoot
+	Selected    []string
+	Cursor      string
+	SortBy      string
+	SortReverse bool
+}
+
+// A SessionRoot is a root with the opened directories under it.
+type SessionRoot struct {
+	Path   string
+	Opened []string
+}
+
+// LoadSession reads the session from the file.
+// When the file doesn't exist, returns the empty session.
+func LoadSession(filename string) (Session, error) {
+	s := Session{}
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return s, err
+	}
+	err = json.Unmarshal(b, &s)
+	return s, err
+}
+
+// Save writes the session into the file.
+func (s Session) Save(filename string) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filename, b)
+}
+
+// dirs creates the roots of the session.
+// The roots which no longer exist are skipped,
+// and so are the opened directories.
+func (s Session) dirs(context *Context) ([]*Dir, error) {
+	roots := []*Dir{}
+	for _, r := range s.Roots {
+		if info, err := os.Stat(r.Path); err != nil || !info.IsDir() {
+			continue
+		}
+		d, err := NewDir(r.Path, context)
+		if err != nil {
+			return nil, err
+		}
+		if err := d.OpenPaths(r.Opened); err != nil {
+			return nil, err
+		}
+		roots = append(roots, d)
+	}
+	return roots, nil
+}
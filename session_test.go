@@ -0,0 +1,125 @@
+package tree_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	tree "github.com/minodisk/go-tree"
+)
+
+func TestSession(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-tree-session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, d := range []string{"a/aa/aaa", "b"} {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0775); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "a", "aa", "x.txt"), []byte{}, 0664); err != nil {
+		t.Fatal(err)
+	}
+	tr, clean := newTree(t, dir)
+	defer clean()
+
+	if err := tr.Toggle(cursorAt(1), noRender); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Toggle(cursorAt(2), noRender); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Select(cursorAt(3), func(int) error { return nil }, noRender); err != nil {
+		t.Fatal(err)
+	}
+	s, err := tr.Session(cursorAt(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(dir, "session", "session.json")
+	if err := s.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+	if s, err = tree.LoadSession(filename); err != nil {
+		t.Fatal(err)
+	}
+	if a, e := s.Cursor, filepath.Join(dir, "a", "aa", "x.txt"); a != e {
+		t.Errorf("Session() should record the path at the cursor, expected '%s', but actual '%s'", e, a)
+	}
+
+	if err := os.RemoveAll(filepath.Join(dir, "a", "aa", "x.txt")); err != nil {
+		t.Fatal(err)
+	}
+	other, clean := newTree(t, filepath.Join(dir, "b"))
+	defer clean()
+	cursor := -1
+	if err := other.RestoreSession(s, func(c int) error {
+		cursor = c
+		return nil
+	}, noRender); err != nil {
+		t.Fatal(err)
+	}
+	a := linesToString(other.Lines())
+	e := filepath.Base(dir) + `/
+- a/
+ - aa/
+  * aaa/
++ b/
++ session/`
+	if a != e {
+		t.Errorf("RestoreSession() should restore the opened directories and the selection\nexpected:\n%s\nactual:\n%s", e, a)
+	}
+	if cursor != 2 {
+		t.Errorf("RestoreSession() should set the cursor on the nearest existing ancestor, expected 2, but actual %d", cursor)
+	}
+}
+
+func TestSessionSort(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-tree-session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	root := filepath.Join(dir, "root")
+	if err := os.MkdirAll(root, 0775); err != nil {
+		t.Fatal(err)
+	}
+	for name, size := range map[string]int{"a.txt": 3, "b.txt": 1, "c.txt": 2} {
+		if err := ioutil.WriteFile(filepath.Join(root, name), make([]byte, size), 0664); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tr, err := tree.New(root, &tree.Context{Config: &tree.Config{
+		TrashDirname:   filepath.Join(dir, "trash"),
+		VisitsFilename: filepath.Join(dir, "visits.json"),
+		SortBy:         tree.SortSize,
+		SortReverse:    true,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := tr.Session(cursorAt(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.SortBy != tree.SortSize || !s.SortReverse {
+		t.Errorf("Session() should record the sort order, but '%s' reversed %t", s.SortBy, s.SortReverse)
+	}
+
+	other, clean := newTree(t, root)
+	defer clean()
+	if err := other.RestoreSession(s, func(int) error { return nil }, noRender); err != nil {
+		t.Fatal(err)
+	}
+	if a, e := linesToString(other.Lines()), linesToString(tr.Lines()); a != e {
+		t.Errorf("RestoreSession() should restore the sort order\nexpected:\n%s\nactual:\n%s", e, a)
+	}
+
+	s.SortBy = "unknown"
+	if err := other.RestoreSession(s, func(int) error { return nil }, noRender); err == nil {
+		t.Errorf("RestoreSession() should fail with the unknown sort order")
+	}
+}
@@ -0,0 +1,191 @@
+package tree
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"os/user"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// NewDirURL creates *Dir from the path or the URL.
+// The URL like sftp://user@host:port/path points the directory
+// on the remote host, and the other paths point the directory on local disk.
+// When the path of the URL is empty, the working directory
+// of the remote host is used.
+func NewDirURL(rawurl string, context *Context) (*Dir, error) {
+	if !strings.HasPrefix(rawurl, "sftp://") {
+		return NewDir(rawurl, context)
+	}
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	client, err := context.SFTPClient(u)
+	if err != nil {
+		return nil, err
+	}
+	p := u.Path
+	if p == "" {
+		p, err = client.Getwd()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return newDir(path.Clean(p), context, SFTPFileSystem(client))
+}
+
+// SFTPClient returns the connection to the host in u.
+// The connections are pooled per user and host.
+func (c *Context) SFTPClient(u *url.URL) (*sftp.Client, error) {
+	key := u.Host
+	if u.User != nil {
+		key = u.User.Username() + "@" + key
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if client, ok := c.sftpClients[key]; ok {
+		return client, nil
+	}
+	dial := c.DialSFTP
+	if dial == nil {
+		dial = dialSFTP
+	}
+	client, err := dial(u)
+	if err != nil {
+		return nil, err
+	}
+	if c.sftpClients == nil {
+		c.sftpClients = map[string]*sftp.Client{}
+	}
+	c.sftpClients[key] = client
+	return client, nil
+}
+
+// Close closes the pooled connections.
+func (c *Context) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var err error
+	for key, client := range c.sftpClients {
+		if e := client.Close(); e != nil && err == nil {
+			err = e
+		}
+		delete(c.sftpClients, key)
+	}
+	return err
+}
+
+func dialSFTP(u *url.URL) (*sftp.Client, error) {
+	var name, password string
+	if u.User != nil {
+		name = u.User.Username()
+		password, _ = u.User.Password()
+	}
+	if name == "" {
+		cu, err := user.Current()
+		if err != nil {
+			return nil, err
+		}
+		name = cu.Username
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "22")
+	}
+
+	auths := []ssh.AuthMethod{}
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		conn, err := net.Dial("unix", sock)
+		if err != nil {
+			return nil, err
+		}
+		defer conn.Close()
+		signers, err := agent.NewClient(conn).Signers()
+		if err != nil {
+			return nil, err
+		}
+		auths = append(auths, ssh.PublicKeys(signers...))
+	}
+	if password != "" {
+		auths = append(auths, ssh.Password(password))
+	}
+	home, err := DirHome()
+	if err != nil {
+		return nil, err
+	}
+	hostKey, err := knownhosts.New(filepath.Join(home, ".ssh", "known_hosts"))
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := ssh.Dial("tcp", host, &ssh.ClientConfig{
+		User:            name,
+		Auth:            auths,
+		HostKeyCallback: hostKey,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can't connect to '%s': %s", host, err)
+	}
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	go func() {
+		client.Wait()
+		conn.Close()
+	}()
+	return client, nil
+}
+
+// SFTPFileSystem returns the FileSystem on the remote host connected with client.
+func SFTPFileSystem(client *sftp.Client) FileSystem {
+	return sftpFS{client: client}
+}
+
+type sftpFS struct {
+	client *sftp.Client
+}
+
+func (fs sftpFS) Stat(name string) (os.FileInfo, error) {
+	return fs.client.Stat(name)
+}
+
+func (fs sftpFS) Lstat(name string) (os.FileInfo, error) {
+	return fs.client.Lstat(name)
+}
+
+func (fs sftpFS) ReadDir(dirname string) ([]os.FileInfo, error) {
+	return fs.client.ReadDir(dirname)
+}
+
+func (fs sftpFS) Open(name string) (io.ReadCloser, error) {
+	return fs.client.Open(name)
+}
+
+func (fs sftpFS) Create(name string) (io.WriteCloser, error) {
+	return fs.client.Create(name)
+}
+
+func (fs sftpFS) MkdirAll(path string) error {
+	return fs.client.MkdirAll(path)
+}
+
+func (fs sftpFS) Rename(oldpath, newpath string) error {
+	return fs.client.PosixRename(oldpath, newpath)
+}
+
+func (fs sftpFS) RemoveAll(path string) error {
+	return fs.client.RemoveAll(path)
+}
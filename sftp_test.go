@@ -0,0 +1,141 @@
+package tree_test
+
+import (
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	tree "github.com/minodisk/go-tree"
+	"github.com/pkg/sftp"
+)
+
+// dialInProcess connects to SFTP server running in this process,
+// which serves local disk.
+func dialInProcess(dials *int) func(*url.URL) (*sftp.Client, error) {
+	return func(*url.URL) (*sftp.Client, error) {
+		*dials++
+		cr, sw := io.Pipe()
+		sr, cw := io.Pipe()
+		server, err := sftp.NewServer(struct {
+			io.Reader
+			io.WriteCloser
+		}{sr, sw})
+		if err != nil {
+			return nil, err
+		}
+		go func() {
+			server.Serve()
+			server.Close()
+		}()
+		return sftp.NewClientPipe(cr, cw)
+	}
+}
+
+func TestSFTP(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-tree-sftp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	remote := filepath.Join(dir, "remote")
+	local := filepath.Join(dir, "local")
+	for _, d := range []string{remote, local} {
+		if err := os.MkdirAll(d, 0775); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(remote, "a.txt"), []byte("a"), 0664); err != nil {
+		t.Fatal(err)
+	}
+
+	var dials int
+	c := &tree.Context{
+		Config:   &tree.Config{TrashDirname: filepath.Join(dir, "trash")},
+		DialSFTP: dialInProcess(&dials),
+	}
+	defer c.Close()
+	tr, err := tree.New("sftp://foo@example.com"+remote, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := tree.NewDirURL("sftp://foo@example.com"+remote, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dials != 1 {
+		t.Errorf("the connection should be pooled per host, but dialed %d times", dials)
+	}
+	if tree.IsLocal(d) {
+		t.Errorf("the directory on the remote host shouldn't be local")
+	}
+
+	if err := d.Open(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.CreateDir("sub"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.CreateFile("b.txt"); err != nil {
+		t.Fatal(err)
+	}
+	o, _ := d.IndexOf(3)
+	if err := tree.Rename(o, "c.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Scan(); err != nil {
+		t.Fatal(err)
+	}
+	o, _ = d.IndexOf(3)
+	if err := tree.Move(o, filepath.Join(remote, "sub")); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Scan(); err != nil {
+		t.Fatal(err)
+	}
+	a := linesToString(d.Lines(0))
+	e := `remote/
++ sub/
+| a.txt`
+	if a != e {
+		t.Errorf("the operations should be done on the remote host\nexpected:\n%s\nactual:\n%s", e, a)
+	}
+	if _, err := os.Stat(filepath.Join(remote, "sub", "c.txt")); err != nil {
+		t.Errorf("the file should be renamed and moved: %s", err)
+	}
+
+	o, _ = d.IndexOf(2)
+	if err := tree.CopyTo(o, filepath.Join(local, "a.txt")); err != nil {
+		t.Fatal(err)
+	}
+	l, err := tree.NewOperator(filepath.Join(local, "a.txt"), c)
+	if err != nil {
+		t.Fatalf("the file should be copied from the remote host: %s", err)
+	}
+	if err := tree.CopyToFS(l, tree.FileSystemOf(d), filepath.Join(remote, "sub", "d.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := ioutil.ReadFile(filepath.Join(remote, "sub", "d.txt")); err != nil || string(b) != "a" {
+		t.Errorf("the file should be copied to the remote host, but got '%s' (%v)", b, err)
+	}
+
+	o, _ = d.IndexOf(1)
+	if err := tree.Remove(o); err != tree.ErrNoTrash {
+		t.Errorf("Remove() should refuse to remove the remote object, but %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(remote, "sub")); err != nil {
+		t.Errorf("the refused directory should remain on the remote host")
+	}
+	if err := tree.RemovePermanently(o); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(remote, "sub")); !os.IsNotExist(err) {
+		t.Errorf("the directory should be removed from the remote host")
+	}
+
+	if _, ok := tr.IndexOf(0); !ok {
+		t.Errorf("the tree should be rooted at the remote directory")
+	}
+}
@@ -3,6 +3,11 @@ package tree
 import (
 	"encoding/base64"
 	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -37,3 +42,231 @@ func (t TrashInfo) Encode() (string, error) {
 	}
 	return base64.StdEncoding.EncodeToString(b), nil
 }
+
+// A TrashEntry represents an object in trash box with its metadata.
+type TrashEntry struct {
+	Operator
+	TrashInfo
+	Size int64
+}
+
+// TrashEntries is a list of TrashEntry ordered from the oldest to the newest.
+type TrashEntries []TrashEntry
+
+// ReadTrash lists the objects in trash box.
+// When the name of an object can't be decoded as TrashInfo,
+// its name and modification time are used instead.
+func ReadTrash(context *Context) (TrashEntries, error) {
+	dirname := context.Config.TrashDirname
+	infos, err := ioutil.ReadDir(dirname)
+	if err != nil {
+		return nil, err
+	}
+	es := TrashEntries{}
+	for _, info := range infos {
+		var o Operator
+		if info.IsDir() {
+			o = &Dir{FileInfo: info, context: context, dirname: dirname}
+		} else {
+			o = &File{FileInfo: info, context: context, dirname: dirname}
+		}
+		ti, err := DecodeTrashInfo(info.Name())
+		if err != nil {
+			ti = TrashInfo{
+				OriginalPath: info.Name(),
+				CreatedAt:    info.ModTime(),
+			}
+		}
+		size, err := Size(o)
+		if err != nil {
+			return nil, err
+		}
+		es = append(es, TrashEntry{
+			Operator:  o,
+			TrashInfo: ti,
+			Size:      size,
+		})
+	}
+	sort.Stable(es)
+	return es, nil
+}
+
+func (es TrashEntries) Len() int {
+	return len(es)
+}
+
+func (es TrashEntries) Swap(i, j int) {
+	es[i], es[j] = es[j], es[i]
+}
+
+func (es TrashEntries) Less(i, j int) bool {
+	return es[i].CreatedAt.Before(es[j].CreatedAt)
+}
+
+// Operators returns the objects of the entries.
+func (es TrashEntries) Operators() Operators {
+	os := Operators{}
+	for _, e := range es {
+		os = append(os, e.Operator)
+	}
+	return os
+}
+
+// Size returns the total size of the entries.
+func (es TrashEntries) Size() int64 {
+	var size int64
+	for _, e := range es {
+		size += e.Size
+	}
+	return size
+}
+
+// OlderThan returns the entries removed before t.
+func (es TrashEntries) OlderThan(t time.Time) TrashEntries {
+	olds := TrashEntries{}
+	for _, e := range es {
+		if e.CreatedAt.Before(t) {
+			olds = append(olds, e)
+		}
+	}
+	return olds
+}
+
+// OverQuota returns the oldest entries which should be purged
+// to keep the total size within quota.
+// When quota is less than or equal to 0, returns no entries.
+func (es TrashEntries) OverQuota(quota int64) TrashEntries {
+	overs := TrashEntries{}
+	if quota <= 0 {
+		return overs
+	}
+	size := es.Size()
+	for _, e := range es {
+		if size <= quota {
+			break
+		}
+		overs = append(overs, e)
+		size -= e.Size
+	}
+	return overs
+}
+
+// NewTrashView creates a virtual directory which shows the objects in trash box
+// under their original parent directories.
+// When Config.TrashGroupByDate is true, the objects are grouped by the date
+// when they were removed.
+func NewTrashView(context *Context) (*Dir, error) {
+	dirname := context.Config.TrashDirname
+	info, err := os.Stat(dirname)
+	if err != nil {
+		return nil, err
+	}
+	d := &Dir{
+		context:  context,
+		FileInfo: virtualInfo{name: info.Name(), modTime: info.ModTime()},
+		dirname:  filepath.Dir(dirname),
+	}
+	d.list = func() (Operators, error) {
+		es, err := ReadTrash(context)
+		if err != nil {
+			return nil, err
+		}
+		if !context.Config.TrashGroupByDate {
+			return trashHierarchy(context, d.Path(), newTrashItems(es), "/"), nil
+		}
+		dates := []string{}
+		groups := map[string]TrashEntries{}
+		for _, e := range es {
+			date := e.CreatedAt.Local().Format("2006-01-02")
+			if _, ok := groups[date]; !ok {
+				dates = append(dates, date)
+			}
+			groups[date] = append(groups[date], e)
+		}
+		os := Operators{}
+		for _, date := range dates {
+			items := newTrashItems(groups[date])
+			path := filepath.Join(d.Path(), date)
+			os = append(os, newVirtualDir(date, d.Path(), context, func() (Operators, error) {
+				return trashHierarchy(context, path, items, "/"), nil
+			}))
+		}
+		return os, nil
+	}
+	return d, nil
+}
+
+// trashItem is a TrashEntry with the remaining components of its original path.
+type trashItem struct {
+	entry TrashEntry
+	parts []string
+}
+
+func newTrashItems(es TrashEntries) []trashItem {
+	items := []trashItem{}
+	for _, e := range es {
+		p := strings.Trim(filepath.ToSlash(e.OriginalPath), "/")
+		items = append(items, trashItem{entry: e, parts: strings.Split(p, "/")})
+	}
+	return items
+}
+
+// trashHierarchy builds the children of the virtual directory at dirname.
+// The items whose original path ends at this level are returned as they are,
+// the others are grouped into virtual directories named after the original
+// parent directories. The chains of directories which have only one
+// directory are joined into one directory to keep the tree shallow.
+func trashHierarchy(context *Context, dirname string, items []trashItem, prefix string) Operators {
+	os := Operators{}
+	names := []string{}
+	groups := map[string][]trashItem{}
+	for _, item := range items {
+		if len(item.parts) <= 1 {
+			os = append(os, item.entry.Operator)
+			continue
+		}
+		n := item.parts[0]
+		if _, ok := groups[n]; !ok {
+			names = append(names, n)
+		}
+		groups[n] = append(groups[n], trashItem{entry: item.entry, parts: item.parts[1:]})
+	}
+	for _, n := range names {
+		children := groups[n]
+		name := prefix + n
+		for {
+			next, ok := commonPart(children)
+			if !ok {
+				break
+			}
+			name = filepath.Join(name, next)
+			for i := range children {
+				children[i].parts = children[i].parts[1:]
+			}
+		}
+		path := filepath.Join(dirname, name)
+		os = append(os, newVirtualDir(name, dirname, context, func() (Operators, error) {
+			return trashHierarchy(context, path, children, ""), nil
+		}))
+	}
+	return os
+}
+
+// commonPart returns the first component shared with all items
+// when none of them ends at this level.
+func commonPart(items []trashItem) (string, bool) {
+	var part string
+	for i, item := range items {
+		if len(item.parts) <= 1 {
+			return "", false
+		}
+		if i == 0 {
+			part = item.parts[0]
+			continue
+		}
+		if item.parts[0] != part {
+			return "", false
+		}
+	}
+	return part, true
+}
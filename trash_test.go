@@ -0,0 +1,235 @@
+package tree_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	tree "github.com/minodisk/go-tree"
+)
+
+func newTrashContext(t *testing.T) (*tree.Context, func()) {
+	dir, err := ioutil.TempDir("", "go-tree-trash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &tree.Context{Config: &tree.Config{TrashDirname: dir}}
+	if err := c.Init(); err != nil {
+		t.Fatal(err)
+	}
+	return c, func() { os.RemoveAll(dir) }
+}
+
+func trash(t *testing.T, c *tree.Context, originalPath string, createdAt time.Time, size int) {
+	name, err := tree.TrashInfo{OriginalPath: originalPath, CreatedAt: createdAt}.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(c.Config.TrashDirname, name), make([]byte, size), 0664); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadTrash(t *testing.T) {
+	c, clean := newTrashContext(t)
+	defer clean()
+
+	now := time.Date(2017, 2, 1, 0, 0, 0, 0, time.UTC)
+	trash(t, c, "/foo/b.txt", now.AddDate(0, 0, -1), 20)
+	trash(t, c, "/foo/a.txt", now.AddDate(0, 0, -10), 10)
+	trash(t, c, "/foo/c.txt", now, 30)
+
+	es, err := tree.ReadTrash(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, e := range []string{"/foo/a.txt", "/foo/b.txt", "/foo/c.txt"} {
+		if a := es[i].OriginalPath; a != e {
+			t.Errorf("ReadTrash()[%d] should be '%s', but actually '%s'", i, e, a)
+		}
+	}
+	if a, e := es.Size(), int64(60); a != e {
+		t.Errorf("Size() expected %d, but actual %d", e, a)
+	}
+	if a, e := es.OlderThan(now.AddDate(0, 0, -5)).Len(), 1; a != e {
+		t.Errorf("OlderThan() expected %d entries, but actual %d", e, a)
+	}
+	if a, e := es.OverQuota(0).Len(), 0; a != e {
+		t.Errorf("OverQuota(0) expected %d entries, but actual %d", e, a)
+	}
+	overs := es.OverQuota(35)
+	if a, e := overs.Len(), 2; a != e {
+		t.Fatalf("OverQuota(35) expected %d entries, but actual %d", e, a)
+	}
+	if a, e := overs[1].OriginalPath, "/foo/b.txt"; a != e {
+		t.Errorf("OverQuota(35) should purge from the oldest, but '%s' is found instead of '%s'", a, e)
+	}
+}
+
+func TestRestoreTo(t *testing.T) {
+	c, clean := newTrashContext(t)
+	defer clean()
+	dir, err := ioutil.TempDir("", "go-tree-restore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	original := filepath.Join(dir, "missing", "parent", "a.txt")
+	trash(t, c, original, time.Date(2017, 2, 1, 0, 0, 0, 0, time.UTC), 10)
+	es, err := tree.ReadTrash(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	occupied := filepath.Join(dir, "b.txt")
+	if err := ioutil.WriteFile(occupied, nil, 0664); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.RestoreTo(es[0], occupied); !os.IsExist(err) {
+		t.Errorf("RestoreTo() should refuse to clobber an existing object, but returns %v", err)
+	}
+
+	if err := tree.Restore(es[0]); err != nil {
+		t.Fatalf("Restore() should recreate missing parent directories, but returns %v", err)
+	}
+	if _, err := os.Stat(original); err != nil {
+		t.Errorf("Restore() should move the object to the original path: %s", err)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	for _, c := range []struct {
+		choice   string
+		dangling bool
+		restored string
+		trashed  int
+	}{
+		{"cancel", false, "", 1},
+		{"rename", false, "root/b.txt", 0},
+		{"elsewhere", false, "other/a.txt", 0},
+		{"overwrite", true, "root/a.txt", 1},
+	} {
+		dir, err := ioutil.TempDir("", "go-tree-restore")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		for _, d := range []string{"root", "other", "trash"} {
+			if err := os.MkdirAll(filepath.Join(dir, d), 0775); err != nil {
+				t.Fatal(err)
+			}
+		}
+		ctx := &tree.Context{Config: &tree.Config{
+			TrashDirname:   filepath.Join(dir, "trash"),
+			VisitsFilename: filepath.Join(dir, "visits.json"),
+		}}
+		tr, err := tree.New(filepath.Join(dir, "trash"), ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		original := filepath.Join(dir, "root", "a.txt")
+		trash(t, ctx, original, time.Date(2017, 2, 1, 0, 0, 0, 0, time.UTC), 3)
+		if c.dangling {
+			if err := os.Symlink(filepath.Join(dir, "gone"), original); err != nil {
+				t.Fatal(err)
+			}
+		} else if err := ioutil.WriteFile(original, []byte("new"), 0664); err != nil {
+			t.Fatal(err)
+		}
+		if err := tr.Scan(); err != nil {
+			t.Fatal(err)
+		}
+
+		choice := c.choice
+		if err := tr.Restore(cursorAt(1), func(...tree.Operator) (bool, error) { return true, nil },
+			func([]string) (string, error) { return choice, nil },
+			func(tree.Operator) (string, error) { return "b.txt", nil },
+			func(tree.Operator) (string, error) { return filepath.Join(dir, "other"), nil },
+			nil, func(int) error { return nil }, noRender); err != nil {
+			t.Fatalf("Restore() with %s should succeed, but %v", c.choice, err)
+		}
+		if c.restored != "" {
+			if info, err := os.Lstat(filepath.Join(dir, c.restored)); err != nil || info.Size() != 3 {
+				t.Errorf("Restore() with %s should restore the object to '%s': %v", c.choice, c.restored, err)
+			}
+		}
+		if !c.dangling {
+			if b, err := ioutil.ReadFile(original); err != nil || string(b) != "new" {
+				t.Errorf("Restore() with %s should leave the object at the original path, but '%s' (%v)", c.choice, b, err)
+			}
+		}
+		if es, err := tree.ReadTrash(ctx); err != nil || es.Len() != c.trashed {
+			t.Errorf("Restore() with %s should leave %d objects in the trash, but %d (%v)", c.choice, c.trashed, es.Len(), err)
+		}
+	}
+}
+
+func TestTrashView(t *testing.T) {
+	c, clean := newTrashContext(t)
+	defer clean()
+
+	now := time.Date(2017, 2, 1, 12, 0, 0, 0, time.Local)
+	trash(t, c, "/home/foo/project/a.txt", now, 10)
+	trash(t, c, "/home/foo/project/lib/b.txt", now, 10)
+	trash(t, c, "/tmp/c.txt", now.AddDate(0, 0, -1), 10)
+
+	d, err := tree.NewTrashView(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.OpenRec(); err != nil {
+		t.Fatal(err)
+	}
+	name := filepath.Base(c.Config.TrashDirname)
+	a := linesToString(d.Lines(0))
+	e := name + `/
+- /home/foo/project/
+ - lib/
+  | b.txt
+ | a.txt
+- /tmp/
+ | c.txt`
+	if a != e {
+		t.Errorf("NewTrashView().Lines() should be\nexpected:\n%s\nactual:\n%s", e, a)
+	}
+
+	c.Config.TrashGroupByDate = true
+	d, err = tree.NewTrashView(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.OpenRec(); err != nil {
+		t.Fatal(err)
+	}
+	a = linesToString(d.Lines(0))
+	e = name + `/
+- 2017-01-31/
+ - /tmp/
+  | c.txt
+- 2017-02-01/
+ - /home/foo/project/
+  - lib/
+   | b.txt
+  | a.txt`
+	if a != e {
+		t.Errorf("NewTrashView().Lines() grouped by date should be\nexpected:\n%s\nactual:\n%s", e, a)
+	}
+
+	o, ok := d.IndexOf(1)
+	if !ok {
+		t.Fatal("IndexOf(1) should return the group of the date")
+	}
+	os, err := tree.Operators{o}.Expand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, e := len(os), 1; a != e {
+		t.Fatalf("Expand() expected %d objects, but actual %d", e, a)
+	}
+	if !tree.IsInTrash(os[0]) || tree.OriginalPath(os[0]) != "/tmp/c.txt" {
+		t.Errorf("Expand() should return the object in trash box, but returns '%s'", os[0].Path())
+	}
+}
@@ -1,12 +1,16 @@
 package tree
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
-
-	shutil "github.com/termie/go-shutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type CursorFunc func() (int, error)
@@ -23,10 +27,17 @@ type OpenFileFunc func(*File) error
 type CancelFunc func() error
 type RenderFunc func([][]byte) error
 type SetClipboardFunc func(string) error
+type TrashEntriesFunc func(TrashEntries) error
+type BookmarksFunc func(Bookmarks) error
+type VisitsFunc func(Visits) error
+type PreviewFunc func(Preview) error
+type HighlightsFunc func([]Highlight) error
 
 type Tree struct {
-	root    *Dir
+	roots   []*Dir
 	context *Context
+	history history
+	cursor  int
 }
 
 func New(path string, context *Context) (*Tree, error) {
@@ -44,29 +55,301 @@ func New(path string, context *Context) (*Tree, error) {
 	return t, nil
 }
 
+// SetRootPath sets the directory at the path as the only root of the tree.
 func (t *Tree) SetRootPath(path string) error {
-	root, err := NewDir(path, t.context)
+	root, err := NewDirURL(path, t.context)
 	if err != nil {
 		return err
 	}
 	return t.SetRoot(root)
 }
 
+// SetRoot replaces all roots of the tree with root.
+// The previous roots are recorded in the history.
 func (t *Tree) SetRoot(root *Dir) error {
-	t.root = root
-	return t.root.Open()
+	t.pushHistory()
+	t.roots = []*Dir{root}
+	t.visit(root)
+	return root.Open()
 }
 
-func (t *Tree) Operator(cursor CursorFunc) (Operator, error) {
+// SetRootPathAt replaces n-th root with the directory at the path.
+func (t *Tree) SetRootPathAt(n int, path string) error {
+	root, err := NewDirURL(path, t.context)
+	if err != nil {
+		return err
+	}
+	return t.SetRootAt(n, root)
+}
+
+// SetRootAt replaces n-th root with root keeping the other roots.
+// The previous roots are recorded in the history.
+func (t *Tree) SetRootAt(n int, root *Dir) error {
+	if n < 0 || n >= len(t.roots) {
+		return fmt.Errorf("no root at %d", n)
+	}
+	t.pushHistory()
+	t.roots[n] = root
+	t.visit(root)
+	return root.Open()
+}
+
+// rootIndex returns the index of the root containing the row at the cursor.
+func (t *Tree) rootIndex(cursor CursorFunc) (int, error) {
+	n, _, err := t.operator(cursor)
+	if err != nil {
+		return -1, err
+	}
+	if n < 0 {
+		return -1, errors.New("no root at the cursor")
+	}
+	return n, nil
+}
+
+// visit records the visit to root in the visits used by Jump.
+// Virtual, archive and remote directories aren't recorded.
+func (t *Tree) visit(root *Dir) {
+	if root.ReadOnly() || !IsLocal(root) {
+		return
+	}
+	t.context.visit(root.Path(), time.Now())
+}
+
+// pushHistory records the current roots with the last known cursor.
+func (t *Tree) pushHistory() {
+	if len(t.roots) == 0 {
+		return
+	}
+	t.history.push(t.snapshot())
+}
+
+func (t *Tree) snapshot() historyEntry {
+	e := historyEntry{
+		roots:  make([]*Dir, len(t.roots)),
+		opened: make([][]string, len(t.roots)),
+		cursor: t.cursor,
+	}
+	copy(e.roots, t.roots)
+	for i, r := range t.roots {
+		e.opened[i] = r.OpenedPaths()
+	}
+	return e
+}
+
+func (t *Tree) restoreHistory(e historyEntry) error {
+	t.roots = e.roots
+	t.cursor = e.cursor
+	for i, r := range t.roots {
+		t.visit(r)
+		if err := r.OpenPaths(e.opened[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *Tree) Back(cursor CursorFunc, setCursor SetCursorFunc, render RenderFunc) error {
+	backs, e, ok := popEntry(t.history.backs)
+	if !ok {
+		return nil
+	}
 	c, err := cursor()
 	if err != nil {
-		return nil, err
+		return err
+	}
+	t.cursor = c
+	t.history.backs = backs
+	t.history.forwards = pushEntry(t.history.forwards, t.snapshot())
+	return t.jump(e, setCursor, render)
+}
+
+func (t *Tree) Forward(cursor CursorFunc, setCursor SetCursorFunc, render RenderFunc) error {
+	forwards, e, ok := popEntry(t.history.forwards)
+	if !ok {
+		return nil
+	}
+	c, err := cursor()
+	if err != nil {
+		return err
+	}
+	t.cursor = c
+	t.history.forwards = forwards
+	t.history.backs = pushEntry(t.history.backs, t.snapshot())
+	return t.jump(e, setCursor, render)
+}
+
+func (t *Tree) jump(e historyEntry, setCursor SetCursorFunc, render RenderFunc) error {
+	if err := t.restoreHistory(e); err != nil {
+		t.Render(render)
+		return err
+	}
+	if err := t.Render(render); err != nil {
+		return err
+	}
+	return setCursor(e.cursor)
+}
+
+// Session returns the state of the tree with the path at the cursor.
+// Remote, virtual and archive roots aren't recorded.
+func (t *Tree) Session(cursor CursorFunc) (Session, error) {
+	s := Session{Roots: []SessionRoot{}, Selected: []string{}}
+	o, err := t.Operator(cursor)
+	if err != nil {
+		return s, err
+	}
+	if o != nil && IsLocal(o) {
+		s.Cursor = o.Path()
+	}
+	if c := t.context.Config; c != nil {
+		s.SortBy, s.SortReverse = c.SortBy, c.SortReverse
+	}
+	for _, r := range t.roots {
+		if r.ReadOnly() || !IsLocal(r) {
+			continue
+		}
+		s.Roots = append(s.Roots, SessionRoot{Path: r.Path(), Opened: r.OpenedPaths()})
+	}
+	for _, o := range t.Selecteds() {
+		if IsLocal(o) {
+			s.Selected = append(s.Selected, o.Path())
+		}
+	}
+	return s, nil
+}
+
+// RestoreSession rebuilds the tree from the session.
+// The paths which no longer exist are skipped, and the cursor is set
+// on the nearest existing ancestor when the object at the cursor has disappeared.
+// The sort order is restored unless the session has none.
+// When none of the roots exists, the tree is left as it is.
+func (t *Tree) RestoreSession(s Session, setCursor SetCursorFunc, render RenderFunc) error {
+	if s.SortBy != "" && t.context.Config != nil {
+		if err := checkSortBy(s.SortBy); err != nil {
+			return err
+		}
+		t.context.Config.SortBy, t.context.Config.SortReverse = s.SortBy, s.SortReverse
+	}
+	roots, err := s.dirs(t.context)
+	if err != nil {
+		return err
+	}
+	if len(roots) == 0 {
+		return t.Render(render)
+	}
+
+	t.pushHistory()
+	t.roots = roots
+	for _, r := range roots {
+		t.visit(r)
+	}
+	for _, p := range s.Selected {
+		if o, err := NewOperator(p, t.context); err == nil {
+			o.Select()
+		}
+	}
+
+	if err := t.Render(render); err != nil {
+		return err
+	}
+	if s.Cursor == "" {
+		return nil
+	}
+	for p := s.Cursor; ; p = filepath.Dir(p) {
+		if i, ok := t.rowOf(LocalFS, p); ok {
+			return setCursor(i)
+		}
+		if p == filepath.Dir(p) {
+			return nil
+		}
+	}
+}
+
+// Roots returns the roots of the tree in the rendered order.
+func (t *Tree) Roots() []*Dir {
+	return t.roots
+}
+
+// AppendRootPath appends the directory at the path or the URL as a root.
+func (t *Tree) AppendRootPath(path string) error {
+	root, err := NewDirURL(path, t.context)
+	if err != nil {
+		return err
+	}
+	return t.AppendRoot(root)
+}
+
+// AppendRoot appends root to the roots of the tree.
+// The directory which is already a root isn't added twice.
+func (t *Tree) AppendRoot(root *Dir) error {
+	for _, r := range t.roots {
+		if FileSystemOf(r) == FileSystemOf(root) && Equals(r, root) {
+			return nil
+		}
 	}
-	o, ok := t.root.IndexOf(c)
+	t.roots = append(t.roots, root)
+	t.visit(root)
+	return root.Open()
+}
+
+// RemoveRootAt removes the i-th root from the tree.
+// The last root can't be removed.
+func (t *Tree) RemoveRootAt(i int) error {
+	if i < 0 || i >= len(t.roots) {
+		return fmt.Errorf("root %d is out of range", i)
+	}
+	if len(t.roots) == 1 {
+		return errors.New("can't remove the last root")
+	}
+	t.roots = append(t.roots[:i], t.roots[i+1:]...)
+	return nil
+}
+
+// rootOf returns the deepest root containing the path of o.
+// When no root contains it, returns the first root.
+func (t *Tree) rootOf(o Operator) *Dir {
+	root := t.roots[0]
+	depth := -1
+	for _, r := range t.roots {
+		if FileSystemOf(r) != FileSystemOf(o) {
+			continue
+		}
+		if ok, err := UnderOrEquals(r, o); err != nil || !ok {
+			continue
+		}
+		if d := len(r.Path()); d > depth {
+			root, depth = r, d
+		}
+	}
+	return root
+}
+
+// locate returns the index of the root containing i-th row and the object at the row.
+func (t *Tree) locate(i int) (int, Operator, bool) {
+	for n, r := range t.roots {
+		if o, ok := r.IndexOf(i); ok {
+			return n, o, true
+		}
+		i -= r.NumLines()
+	}
+	return -1, nil, false
+}
+
+func (t *Tree) operator(cursor CursorFunc) (int, Operator, error) {
+	c, err := cursor()
+	if err != nil {
+		return -1, nil, err
+	}
+	t.cursor = c
+	n, o, ok := t.locate(c)
 	if !ok {
 		//
 	}
-	return o, nil
+	return n, o, nil
+}
+
+func (t *Tree) Operator(cursor CursorFunc) (Operator, error) {
+	_, o, err := t.operator(cursor)
+	return o, err
 }
 
 type SelectedRangeFunc func() (Range, error)
@@ -76,73 +359,346 @@ func (t *Tree) Operators(selectedRange SelectedRangeFunc) (Operators, error) {
 	if err != nil {
 		return nil, err
 	}
-	return t.root.ObjectsAt(r), nil
+	return t.ObjectsAt(r), nil
+}
+
+// ObjectsAt returns the objects in the range of rows across all roots.
+func (t *Tree) ObjectsAt(r Range) Operators {
+	os := Operators{}
+	offset := 0
+	for _, root := range t.roots {
+		if r.End < offset {
+			break
+		}
+		os = append(os, root.ObjectsAt(Range{Start: r.Start - offset, End: r.End - offset})...)
+		offset += root.NumLines()
+	}
+	return os
 }
 
 func (t *Tree) IndexOf(i int) (Operator, bool) {
-	return t.root.IndexOf(i)
+	_, o, ok := t.locate(i)
+	return o, ok
+}
+
+// rowOf returns the index of the first row showing the object at the path in fs.
+func (t *Tree) rowOf(fs FileSystem, p string) (int, bool) {
+	for i, o := range t.All() {
+		if o.Path() == p && FileSystemOf(o) == fs {
+			return i, true
+		}
+	}
+	return -1, false
 }
 
+// HasSelected returns that any object is selected.
+// The selected objects which no longer exist are found by Selecteds.
 func (t *Tree) HasSelected() bool {
-	return t.root.HasSelected()
+	return t.context.hasSelected()
+}
+
+// Selecteds returns the selected objects shown across all roots in the rendered order,
+// followed by the selected ones hidden in closed directories or outside the roots.
+func (t *Tree) Selecteds() Operators {
+	os := Operators{}
+	shown := map[position]bool{}
+	for _, r := range t.roots {
+		for _, o := range r.Selecteds() {
+			if p := positionOf(o); !shown[p] {
+				shown[p] = true
+				os = append(os, o)
+			}
+		}
+	}
+	return append(os, t.hiddenSelecteds(shown)...)
+}
+
+// hiddenSelecteds returns the selected objects which aren't shown.
+// The ones which no longer exist are unselected.
+func (t *Tree) hiddenSelecteds(shown map[position]bool) Operators {
+	os := Operators{}
+	for _, o := range t.context.selecteds() {
+		if shown[positionOf(o)] {
+			continue
+		}
+		if !exists(o) {
+			o.Unselect()
+			continue
+		}
+		os = append(os, o)
+	}
+	return os
+}
+
+// HiddenSelecteds passes the selected objects hidden in closed directories
+// or outside the roots.
+func (t *Tree) HiddenSelecteds(operators OperatorsFunc) error {
+	shown := map[position]bool{}
+	for _, o := range t.All() {
+		shown[positionOf(o)] = true
+	}
+	return operators(t.hiddenSelecteds(shown))
+}
+
+// All returns the objects in all roots.
+func (t *Tree) All() Operators {
+	os := Operators{}
+	for _, r := range t.roots {
+		os = append(os, r.All()...)
+	}
+	return os
+}
+
+// Lines returns the rendered rows of all roots.
+func (t *Tree) Lines() [][]byte {
+	lines := [][]byte{}
+	for _, r := range t.roots {
+		lines = append(lines, r.Rows()...)
+	}
+	return lines
+}
+
+// Highlights returns the ranges of the names in the rows rendered by Lines
+// with the highlight groups assigned by Config.LSColors.
+// Start and End are the offsets in bytes.
+func (t *Tree) Highlights() []Highlight {
+	lines := t.Lines()
+	colors := t.context.Config.lsColors
+	hs := []Highlight{}
+	for i, o := range t.All() {
+		name := []byte(displayName(o))
+		start := bytes.LastIndex(lines[i], name)
+		if start == -1 {
+			continue
+		}
+		group, color := colors.Highlight(o)
+		hs = append(hs, Highlight{Row: i, Start: start, End: start + len(name), Group: group, Color: color})
+	}
+	return hs
+}
+
+func (t *Tree) Highlight(highlights HighlightsFunc) error {
+	return highlights(t.Highlights())
 }
 
 func (t *Tree) Render(render RenderFunc) error {
-	return render(t.root.Lines(0))
+	return render(t.Lines())
+}
+
+func (t *Tree) Scan() error {
+	for _, r := range t.roots {
+		if err := r.Scan(); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (t *Tree) ScanAndRender(render RenderFunc) error {
-	t.root.Scan()
+	t.Scan()
 	return t.Render(render)
 }
 
 func (t *Tree) Open(render RenderFunc) error {
 	defer t.Render(render)
-	return t.root.Open()
+	for _, r := range t.roots {
+		if err := r.Open(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *Tree) AddRoot(path TextFunc, render RenderFunc) error {
+	defer t.Render(render)
+	p, err := path()
+	if err != nil {
+		return err
+	}
+	if p == "" {
+		return nil
+	}
+	return t.AppendRootPath(p)
+}
+
+func (t *Tree) RemoveRoot(cursor CursorFunc, render RenderFunc) error {
+	defer t.Render(render)
+	n, _, err := t.operator(cursor)
+	if err != nil {
+		return err
+	}
+	return t.RemoveRootAt(n)
 }
 
-func (t *Tree) CD(path TextFunc, render RenderFunc) error {
+// CD replaces the root under the cursor with the directory at the path.
+func (t *Tree) CD(cursor CursorFunc, path TextFunc, render RenderFunc) error {
 	defer t.Render(render)
+	n, err := t.rootIndex(cursor)
+	if err != nil {
+		return err
+	}
 	p, err := path()
 	if err != nil {
 		return err
 	}
-	return t.SetRootPath(p)
+	return t.SetRootPathAt(n, p)
 }
 
-func (t *Tree) Root(render RenderFunc) error {
+// Root replaces the root under the cursor with the root directory of the file system.
+func (t *Tree) Root(cursor CursorFunc, render RenderFunc) error {
 	defer t.Render(render)
-	return t.SetRootPath(DirRoot())
+	n, err := t.rootIndex(cursor)
+	if err != nil {
+		return err
+	}
+	return t.SetRootPathAt(n, DirRoot())
 }
 
-func (t *Tree) Home(render RenderFunc) error {
+// Home replaces the root under the cursor with the home directory.
+func (t *Tree) Home(cursor CursorFunc, render RenderFunc) error {
 	defer t.Render(render)
+	n, err := t.rootIndex(cursor)
+	if err != nil {
+		return err
+	}
 	dir, err := DirHome()
 	if err != nil {
 		return err
 	}
-	return t.SetRootPath(dir)
+	return t.SetRootPathAt(n, dir)
 }
 
-func (t *Tree) Trash(render RenderFunc) error {
+// Trash replaces the root under the cursor with trash box.
+func (t *Tree) Trash(cursor CursorFunc, render RenderFunc) error {
 	defer t.Render(render)
-	return t.SetRootPath(t.context.Config.TrashDirname)
+	n, err := t.rootIndex(cursor)
+	if err != nil {
+		return err
+	}
+	return t.SetRootPathAt(n, t.context.Config.TrashDirname)
+}
+
+// TrashView replaces the root under the cursor with the view of trash box.
+func (t *Tree) TrashView(cursor CursorFunc, render RenderFunc) error {
+	defer t.Render(render)
+	n, err := t.rootIndex(cursor)
+	if err != nil {
+		return err
+	}
+	root, err := NewTrashView(t.context)
+	if err != nil {
+		return err
+	}
+	return t.SetRootAt(n, root)
+}
+
+// RevealPolicy decides how Reveal roots the path outside the roots.
+type RevealPolicy int
+
+const (
+	// RevealFail returns an error for the path outside the roots.
+	RevealFail RevealPolicy = iota
+	// RevealParent sets the directory containing the path as the only root.
+	RevealParent
+	// RevealProject sets the project containing the path as the only root.
+	RevealProject
+	// RevealAppend appends the directory containing the path as a root.
+	RevealAppend
+)
+
+// Reveal opens the directories between the root and the local object at the path,
+// and returns the index of the row showing it.
+// When no root contains the path, the tree is re-rooted with policy.
+func (t *Tree) Reveal(path string, policy RevealPolicy, render RenderFunc) (int, error) {
+	defer t.Render(render)
+
+	p, err := filepath.Abs(path)
+	if err != nil {
+		return -1, err
+	}
+	if _, err := os.Stat(p); err != nil {
+		return -1, err
+	}
+
+	root, ok := t.localRootOf(p)
+	if !ok {
+		dirname := filepath.Dir(p)
+		switch policy {
+		case RevealParent:
+			err = t.SetRootPath(dirname)
+		case RevealProject:
+			dirname, err = DirProject(dirname, t.context.Config.rProject)
+			if err == nil {
+				err = t.SetRootPath(dirname)
+			}
+		case RevealAppend:
+			err = t.AppendRootPath(dirname)
+		default:
+			err = fmt.Errorf("'%s' is outside the roots", p)
+		}
+		if err != nil {
+			return -1, err
+		}
+		if root, ok = t.localRootOf(p); !ok {
+			return -1, fmt.Errorf("'%s' is outside the roots", p)
+		}
+	}
+
+	o, err := root.reveal(p)
+	if err != nil {
+		return -1, err
+	}
+	for i, r := range t.All() {
+		if r == o {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("'%s' isn't shown", p)
+}
+
+// localRootOf returns the deepest local root containing the path.
+func (t *Tree) localRootOf(p string) (*Dir, bool) {
+	var root *Dir
+	var rootPath string
+	for _, r := range t.roots {
+		if r.ReadOnly() || !IsLocal(r) {
+			continue
+		}
+		// The roots made from relative paths are compared as absolute.
+		rp, err := filepath.Abs(r.Path())
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(rp, p)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if root == nil || len(rp) > len(rootPath) {
+			root, rootPath = r, rp
+		}
+	}
+	return root, root != nil
 }
 
-func (t *Tree) Project(render RenderFunc) error {
+// Project replaces the root under the cursor with the project containing it.
+func (t *Tree) Project(cursor CursorFunc, render RenderFunc) error {
 	defer t.Render(render)
 
-	p, err := DirProject(t.root.Path(), t.context.Config.rProject)
+	n, err := t.rootIndex(cursor)
+	if err != nil {
+		return err
+	}
+	p, err := DirProject(t.roots[n].Path(), t.context.Config.rProject)
 	if err != nil {
 		return err
 	}
-	return t.SetRootPath(p)
+	return t.SetRootPathAt(n, p)
 }
 
 func (t *Tree) Up(cursor CursorFunc, render RenderFunc) error {
 	defer t.Render(render)
 
-	o, err := t.Operator(cursor)
+	n, o, err := t.operator(cursor)
 	if err != nil {
 		return err
 	}
@@ -158,7 +714,7 @@ func (t *Tree) Up(cursor CursorFunc, render RenderFunc) error {
 
 	// When target directory is lower than or equal to root,
 	// just close current directory.
-	isUnder, err := UnderOrEquals(t.root, next)
+	isUnder, err := UnderOrEquals(t.roots[n], next)
 	if err != nil {
 		return err
 	}
@@ -168,22 +724,27 @@ func (t *Tree) Up(cursor CursorFunc, render RenderFunc) error {
 	}
 
 	// The other cases, set target directory as root.
-	t.root = next
-	return t.root.Open()
+	return t.SetRootAt(n, next)
 }
 
 func (t *Tree) Down(cursor CursorFunc, openFile OpenFileFunc, render RenderFunc) error {
 	defer t.Render(render)
 
-	o, err := t.Operator(cursor)
+	n, o, err := t.operator(cursor)
 	if err != nil {
 		return err
 	}
 
 	switch o := o.(type) {
 	case *Dir:
-		t.root = o
-		return t.root.Open()
+		if IsArchive(o) {
+			f, err := newFile(o.Path(), t.context, LocalFS)
+			if err != nil {
+				return err
+			}
+			return openFile(f)
+		}
+		return t.SetRootAt(n, o)
 	case *File:
 		return openFile(o)
 	default:
@@ -210,7 +771,7 @@ func (t *Tree) Select(cursor CursorFunc, setCursorFunc SetCursorFunc, render Ren
 }
 
 func (t *Tree) ReverseSelected(render RenderFunc) error {
-	for _, o := range t.root.All() {
+	for _, o := range t.All() {
 		if o.Selected() {
 			o.Unselect()
 		} else {
@@ -220,29 +781,155 @@ func (t *Tree) ReverseSelected(render RenderFunc) error {
 	return t.Render(render)
 }
 
-func (t *Tree) Toggle(cursor CursorFunc, render RenderFunc) error {
+// SelectRange selects the objects at the rows in the range.
+// Returns the number of the newly selected objects.
+func (t *Tree) SelectRange(selectedRange SelectedRangeFunc, render RenderFunc) (int, error) {
 	defer t.Render(render)
 
-	o, err := t.Operator(cursor)
+	os, err := t.Operators(selectedRange)
 	if err != nil {
-		return err
+		return 0, err
 	}
-	return Toggle(o)
+	n := 0
+	for _, o := range os {
+		if !o.Selected() {
+			o.Select()
+			n++
+		}
+	}
+	return n, nil
 }
 
-func (t *Tree) ToggleRec(cursor CursorFunc, render RenderFunc) error {
+// SelectAll selects the children of the opened directory at the cursor,
+// or the siblings of the other object at the cursor.
+// Returns the number of the newly selected objects.
+func (t *Tree) SelectAll(cursor CursorFunc, render RenderFunc) (int, error) {
 	defer t.Render(render)
 
 	o, err := t.Operator(cursor)
 	if err != nil {
-		return err
+		return 0, err
 	}
-	return ToggleRec(o)
+	n := 0
+	for _, c := range openedDirOf(o).children {
+		if !c.Selected() {
+			c.Select()
+			n++
+		}
+	}
+	return n, nil
+}
+
+// ClearSelection unselects all objects.
+// Returns the number of the unselected objects.
+func (t *Tree) ClearSelection(render RenderFunc) (int, error) {
+	defer t.Render(render)
+
+	os := t.Selecteds()
+	os.Unselect()
+	return len(os), nil
+}
+
+// SelectScope is the range of the objects matched by SelectGlob and SelectRegexp.
+type SelectScope int
+
+const (
+	// SelectShown matches the shown descendants of the directory at the cursor.
+	SelectShown SelectScope = iota
+	// SelectDescendants matches all descendants of the directory at the cursor,
+	// and opens the directories containing the matched ones.
+	SelectDescendants
+)
+
+// SelectGlob selects the objects whose names match the glob pattern.
+// Returns the number of the newly selected objects.
+func (t *Tree) SelectGlob(cursor CursorFunc, pattern TextFunc, scope SelectScope, render RenderFunc) (int, error) {
+	defer t.Render(render)
+
+	p, err := pattern()
+	if err != nil {
+		return 0, err
+	}
+	if p == "" {
+		return 0, nil
+	}
+	if _, err := filepath.Match(p, ""); err != nil {
+		return 0, err
+	}
+	return t.selectMatched(cursor, scope, func(d *Dir, o Operator) bool {
+		ok, _ := filepath.Match(p, o.Name())
+		return ok
+	})
+}
+
+// SelectRegexp selects the objects whose paths relative to the directory
+// at the cursor match the regular expression.
+// Returns the number of the newly selected objects.
+func (t *Tree) SelectRegexp(cursor CursorFunc, pattern TextFunc, scope SelectScope, render RenderFunc) (int, error) {
+	defer t.Render(render)
+
+	p, err := pattern()
+	if err != nil {
+		return 0, err
+	}
+	if p == "" {
+		return 0, nil
+	}
+	r, err := regexp.Compile(p)
+	if err != nil {
+		return 0, err
+	}
+	return t.selectMatched(cursor, scope, func(d *Dir, o Operator) bool {
+		rel, err := filepath.Rel(d.Path(), o.Path())
+		return err == nil && r.MatchString(filepath.ToSlash(rel))
+	})
+}
+
+func (t *Tree) selectMatched(cursor CursorFunc, scope SelectScope, match func(*Dir, Operator) bool) (int, error) {
+	o, err := t.Operator(cursor)
+	if err != nil {
+		return 0, err
+	}
+	d := openedDirOf(o)
+	_, n, err := d.selectMatched(func(o Operator) bool {
+		return match(d, o)
+	}, scope == SelectDescendants)
+	return n, err
+}
+
+// openedDirOf returns o when it is an opened directory,
+// otherwise the directory containing o.
+func openedDirOf(o Operator) *Dir {
+	if d, ok := o.(*Dir); ok && (d.Opened() || d.Parent() == nil) {
+		return d
+	}
+	return o.Parent()
 }
 
-func (t *Tree) CreateDir(cursor CursorFunc, texts TextsFunc, render RenderFunc) error {
+func (t *Tree) Toggle(cursor CursorFunc, render RenderFunc) error {
 	defer t.Render(render)
 
+	o, err := t.Operator(cursor)
+	if err != nil {
+		return err
+	}
+	return Toggle(o)
+}
+
+func (t *Tree) ToggleRec(cursor CursorFunc, render RenderFunc) error {
+	defer t.Render(render)
+
+	o, err := t.Operator(cursor)
+	if err != nil {
+		return err
+	}
+	return ToggleRec(o)
+}
+
+func (t *Tree) CreateDir(cursor CursorFunc, texts TextsFunc, setCursor SetCursorFunc, render RenderFunc) error {
+	tr := t.track(cursor)
+	defer tr.scanAndRender(setCursor, render)
+
 	o, err := t.Operator(cursor)
 	if err != nil {
 		return err
@@ -251,11 +938,19 @@ func (t *Tree) CreateDir(cursor CursorFunc, texts TextsFunc, render RenderFunc)
 	if err != nil {
 		return err
 	}
+	dirname := o.Dirname()
+	if d, ok := o.(*Dir); ok && !IsArchive(d) {
+		dirname = d.Path()
+	}
+	for _, n := range names {
+		tr.target(FileSystemOf(o), filepath.Join(dirname, n))
+	}
 	return CreateDir(o, names...)
 }
 
-func (t *Tree) CreateFile(cursor CursorFunc, texts TextsFunc, render RenderFunc) error {
-	defer t.Render(render)
+func (t *Tree) CreateFile(cursor CursorFunc, texts TextsFunc, setCursor SetCursorFunc, render RenderFunc) error {
+	tr := t.track(cursor)
+	defer tr.scanAndRender(setCursor, render)
 
 	o, err := t.Operator(cursor)
 	if err != nil {
@@ -265,14 +960,22 @@ func (t *Tree) CreateFile(cursor CursorFunc, texts TextsFunc, render RenderFunc)
 	if err != nil {
 		return err
 	}
+	dirname := o.Dirname()
+	if d, ok := o.(*Dir); ok && !IsArchive(d) {
+		dirname = d.Path()
+	}
+	for _, n := range names {
+		tr.target(FileSystemOf(o), filepath.Join(dirname, n))
+	}
 	return CreateFile(o, names...)
 }
 
-func (t *Tree) Rename(cursor CursorFunc, text OperatorTextFunc, texts OperatorsTextsFunc, cancel CancelFunc, render RenderFunc) error {
-	defer t.ScanAndRender(render)
+func (t *Tree) Rename(cursor CursorFunc, text OperatorTextFunc, texts OperatorsTextsFunc, cancel CancelFunc, setCursor SetCursorFunc, render RenderFunc) error {
+	tr := t.track(cursor)
+	defer tr.scanAndRender(setCursor, render)
 
 	if t.HasSelected() {
-		os := t.root.Selecteds()
+		os := t.Selecteds()
 		defer os.Unselect()
 		names, err := texts(os)
 		if err != nil {
@@ -288,6 +991,7 @@ func (t *Tree) Rename(cursor CursorFunc, text OperatorTextFunc, texts OperatorsT
 			if err := Rename(o, n); err != nil {
 				return err
 			}
+			tr.target(FileSystemOf(o), filepath.Join(o.Dirname(), n))
 		}
 	}
 
@@ -299,14 +1003,16 @@ func (t *Tree) Rename(cursor CursorFunc, text OperatorTextFunc, texts OperatorsT
 	if err != nil {
 		return err
 	}
+	tr.target(FileSystemOf(o), filepath.Join(o.Dirname(), new))
 	return Rename(o, new)
 }
 
-func (t *Tree) Move(cursor CursorFunc, text OperatorsTextFunc, cancel CancelFunc, render RenderFunc) error {
-	defer t.ScanAndRender(render)
+func (t *Tree) Move(cursor CursorFunc, text OperatorsTextFunc, cancel CancelFunc, setCursor SetCursorFunc, render RenderFunc) error {
+	tr := t.track(cursor)
+	defer tr.scanAndRender(setCursor, render)
 
 	if t.HasSelected() {
-		os := t.root.Selecteds()
+		os := t.Selecteds()
 		defer os.Unselect()
 		path, err := text(os)
 		if err != nil {
@@ -316,9 +1022,11 @@ func (t *Tree) Move(cursor CursorFunc, text OperatorsTextFunc, cancel CancelFunc
 			return cancel()
 		}
 		for _, o := range os {
-			if err := Move(o, filepath.Join(t.root.Path(), path)); err != nil {
+			dst := filepath.Join(t.rootOf(o).Path(), path)
+			if err := Move(o, dst); err != nil {
 				return err
 			}
+			tr.target(FileSystemOf(o), filepath.Join(dst, o.Name()))
 		}
 		return nil
 	}
@@ -331,15 +1039,25 @@ func (t *Tree) Move(cursor CursorFunc, text OperatorsTextFunc, cancel CancelFunc
 	if err != nil {
 		return err
 	}
-	return Move(o, filepath.Join(t.root.Path(), path))
+	dst := filepath.Join(t.rootOf(o).Path(), path)
+	tr.target(FileSystemOf(o), filepath.Join(dst, o.Name()))
+	return Move(o, dst)
 }
 
-func (t *Tree) Remove(cursor CursorFunc, confirm ConfirmFunc, cancel CancelFunc, render RenderFunc) error {
-	defer t.ScanAndRender(render)
+// Remove moves the objects to trash box.
+// Fails with ErrNoTrash before confirming when any of them is on a remote server,
+// which has to be removed with RemovePermanently.
+func (t *Tree) Remove(cursor CursorFunc, confirm ConfirmFunc, cancel CancelFunc, setCursor SetCursorFunc, render RenderFunc) error {
+	defer t.track(cursor).scanAndRender(setCursor, render)
 
 	if t.HasSelected() {
-		os := t.root.Selecteds()
+		os := t.Selecteds()
 		defer os.Unselect()
+		for _, o := range os {
+			if !IsLocal(o) {
+				return ErrNoTrash
+			}
+		}
 		ok, err := confirm(os...)
 		if err != nil {
 			return err
@@ -359,6 +1077,9 @@ func (t *Tree) Remove(cursor CursorFunc, confirm ConfirmFunc, cancel CancelFunc,
 	if err != nil {
 		return err
 	}
+	if !IsLocal(o) {
+		return ErrNoTrash
+	}
 	ok, err := confirm(o)
 	if err != nil {
 		return err
@@ -369,12 +1090,16 @@ func (t *Tree) Remove(cursor CursorFunc, confirm ConfirmFunc, cancel CancelFunc,
 	return Remove(o)
 }
 
-func (t *Tree) RemovePermanently(cursor CursorFunc, confirm ConfirmFunc, cancel CancelFunc, render RenderFunc) error {
-	defer t.ScanAndRender(render)
+func (t *Tree) RemovePermanently(cursor CursorFunc, confirm ConfirmFunc, cancel CancelFunc, setCursor SetCursorFunc, render RenderFunc) error {
+	defer t.track(cursor).scanAndRender(setCursor, render)
 
 	if t.HasSelected() {
-		os := t.root.Selecteds()
-		defer os.Unselect()
+		selecteds := t.Selecteds()
+		defer selecteds.Unselect()
+		os, err := selecteds.Expand()
+		if err != nil {
+			return err
+		}
 		ok, err := confirm(os...)
 		if err != nil {
 			return err
@@ -394,22 +1119,35 @@ func (t *Tree) RemovePermanently(cursor CursorFunc, confirm ConfirmFunc, cancel
 	if err != nil {
 		return err
 	}
-	ok, err := confirm(o)
+	os, err := Operators{o}.Expand()
+	if err != nil {
+		return err
+	}
+	ok, err := confirm(os...)
 	if err != nil {
 		return err
 	}
 	if !ok {
 		return cancel()
 	}
-	return RemovePermanently(o)
+	for _, o := range os {
+		if err := RemovePermanently(o); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func (t *Tree) Restore(cursor CursorFunc, confirm ConfirmFunc, cancel CancelFunc, render RenderFunc) error {
-	defer t.ScanAndRender(render)
+func (t *Tree) Restore(cursor CursorFunc, confirm ConfirmFunc, choose ChooseFunc, rename OperatorTextFunc, elsewhere OperatorTextFunc, cancel CancelFunc, setCursor SetCursorFunc, render RenderFunc) error {
+	defer t.track(cursor).scanAndRender(setCursor, render)
 
 	if t.HasSelected() {
-		os := t.root.Selecteds()
-		defer os.Unselect()
+		selecteds := t.Selecteds()
+		defer selecteds.Unselect()
+		os, err := selecteds.Expand()
+		if err != nil {
+			return err
+		}
 		ok, err := confirm(os...)
 		if err != nil {
 			return err
@@ -418,7 +1156,7 @@ func (t *Tree) Restore(cursor CursorFunc, confirm ConfirmFunc, cancel CancelFunc
 			return cancel()
 		}
 		for _, o := range os {
-			if err := Restore(o); err != nil {
+			if err := t.restore(o, choose, rename, elsewhere); err != nil {
 				return err
 			}
 		}
@@ -429,21 +1167,156 @@ func (t *Tree) Restore(cursor CursorFunc, confirm ConfirmFunc, cancel CancelFunc
 	if err != nil {
 		return err
 	}
-	ok, err := confirm(o)
+	os, err := Operators{o}.Expand()
+	if err != nil {
+		return err
+	}
+	ok, err := confirm(os...)
 	if err != nil {
 		return err
 	}
 	if !ok {
 		return cancel()
 	}
-	return Restore(o)
+	for _, o := range os {
+		if err := t.restore(o, choose, rename, elsewhere); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restore restores o to the original path.
+// While the destination is occupied, asks how to resolve the conflict.
+func (t *Tree) restore(o Operator, choose ChooseFunc, rename OperatorTextFunc, elsewhere OperatorTextFunc) error {
+	if !IsInTrash(o) {
+		return nil
+	}
+	dstPath := OriginalPath(o)
+	var overwritten Operator
+	for overwritten == nil {
+		info, err := os.Lstat(dstPath)
+		if err != nil {
+			break
+		}
+		cs := []string{"overwrite", "rename", "elsewhere", "cancel"}
+		c, err := choose(cs)
+		if err != nil {
+			return err
+		}
+		switch c {
+		case "overwrite":
+			// The object at the path is made from Lstat,
+			// so that a symbolic link is overwritten even if dangling.
+			if info.IsDir() {
+				if overwritten, err = NewDir(dstPath, t.context); err != nil {
+					return err
+				}
+			} else {
+				overwritten = &File{FileInfo: info, context: t.context, dirname: filepath.Dir(dstPath), fs: LocalFS}
+			}
+		case "rename":
+			newName, err := rename(o)
+			if err != nil {
+				return err
+			}
+			if newName == "" {
+				return nil
+			}
+			dstPath = filepath.Join(filepath.Dir(dstPath), newName)
+		case "elsewhere":
+			dir, err := elsewhere(o)
+			if err != nil {
+				return err
+			}
+			if dir == "" {
+				return nil
+			}
+			if !filepath.IsAbs(dir) {
+				dir = filepath.Join(t.rootOf(o).Path(), dir)
+			}
+			dstPath = filepath.Join(dir, filepath.Base(dstPath))
+		default:
+			return nil
+		}
+	}
+	return restoreTo(o, dstPath, overwritten)
+}
+
+func (t *Tree) TrashList(entries TrashEntriesFunc) error {
+	es, err := ReadTrash(t.context)
+	if err != nil {
+		return err
+	}
+	return entries(es)
+}
+
+func (t *Tree) EmptyTrash(confirm ConfirmFunc, cancel CancelFunc, render RenderFunc) error {
+	defer t.ScanAndRender(render)
+
+	es, err := ReadTrash(t.context)
+	if err != nil {
+		return err
+	}
+	return t.purge(es, confirm, cancel)
+}
+
+func (t *Tree) PurgeTrash(days TextFunc, confirm ConfirmFunc, cancel CancelFunc, render RenderFunc) error {
+	defer t.ScanAndRender(render)
+
+	d, err := days()
+	if err != nil {
+		return err
+	}
+	if d == "" {
+		return cancel()
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(d))
+	if err != nil {
+		return err
+	}
+	es, err := ReadTrash(t.context)
+	if err != nil {
+		return err
+	}
+	return t.purge(es.OlderThan(time.Now().AddDate(0, 0, -n)), confirm, cancel)
+}
+
+func (t *Tree) EnforceTrashQuota(confirm ConfirmFunc, cancel CancelFunc, render RenderFunc) error {
+	defer t.ScanAndRender(render)
+
+	es, err := ReadTrash(t.context)
+	if err != nil {
+		return err
+	}
+	return t.purge(es.OverQuota(t.context.Config.TrashQuota), confirm, cancel)
+}
+
+func (t *Tree) purge(es TrashEntries, confirm ConfirmFunc, cancel CancelFunc) error {
+	if es.Len() == 0 {
+		return nil
+	}
+	os := es.Operators()
+	ok, err := confirm(os...)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return cancel()
+	}
+	for _, o := range os {
+		if err := RemovePermanently(o); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (t *Tree) OpenExternally(cursor CursorFunc, render RenderFunc) error {
 	defer t.ScanAndRender(render)
 
 	if t.HasSelected() {
-		os := t.root.Selecteds()
+		os := t.Selecteds()
 		defer os.Unselect()
 		for _, o := range os {
 			if err := OpenWithOS(o); err != nil {
@@ -464,7 +1337,7 @@ func (t *Tree) OpenDirExternally(cursor CursorFunc, render RenderFunc) error {
 	defer t.ScanAndRender(render)
 
 	if t.HasSelected() {
-		os := t.root.Selecteds()
+		os := t.Selecteds()
 		defer os.Unselect()
 		for _, o := range os {
 			if err := OpenWithOS(NearestDir(o)); err != nil {
@@ -483,7 +1356,7 @@ func (t *Tree) OpenDirExternally(cursor CursorFunc, render RenderFunc) error {
 
 func (t *Tree) Copy(cursor CursorFunc) error {
 	if t.HasSelected() {
-		os := t.root.Selecteds()
+		os := t.Selecteds()
 		defer os.Unselect()
 		t.context.Registry = os
 		return nil
@@ -503,8 +1376,9 @@ func (t *Tree) CopiedList(operators OperatorsFunc) error {
 
 type ChooseFunc func([]string) (string, error)
 
-func (t *Tree) Paste(cursor CursorFunc, choose ChooseFunc, rename OperatorTextFunc, render RenderFunc) error {
-	defer t.ScanAndRender(render)
+func (t *Tree) Paste(cursor CursorFunc, choose ChooseFunc, rename OperatorTextFunc, setCursor SetCursorFunc, render RenderFunc) error {
+	tr := t.track(cursor)
+	defer tr.scanAndRender(setCursor, render)
 
 	if t.context.Registry.Len() == 0 {
 		return nil
@@ -517,50 +1391,421 @@ func (t *Tree) Paste(cursor CursorFunc, choose ChooseFunc, rename OperatorTextFu
 	if err != nil {
 		return err
 	}
+	if d.ReadOnly() {
+		return ErrReadOnly
+	}
+	fs := d.FileSystem()
 	dstDir := d.Path()
 	for _, o := range t.context.Registry {
 		dstPath := filepath.Join(dstDir, o.Name())
-		if o.Path() == dstPath {
+		if FileSystemOf(o) == fs && o.Path() == dstPath {
 			continue
 		}
-		if info, err := os.Stat(dstPath); err == nil {
-			cs := []string{"overwrite", "rename", "cancel"}
-			c, err := choose(cs)
+		dstPath, err := t.pasteTo(o, fs, dstPath, choose, rename)
+		if err != nil {
+			return err
+		}
+		if dstPath != "" {
+			tr.target(fs, dstPath)
+		}
+	}
+	return nil
+}
+
+// pasteTo copies o to dstPath in fs.
+// When an object exists at dstPath, choose decides to overwrite it,
+// to copy with the other name or to cancel.
+// The overwritten object is moved to trash box, or removed permanently on remote servers.
+// Returns the path of the copied object, or the empty string when canceled.
+func (t *Tree) pasteTo(o Operator, fs FileSystem, dstPath string, choose ChooseFunc, rename OperatorTextFunc) (string, error) {
+	removeOverwritten := func() error { return nil }
+	if info, err := fs.Stat(dstPath); err == nil {
+		// The objects on remote servers can't be moved to trash box,
+		// so overwriting them is offered as removing them permanently.
+		overwrite, remove := "overwrite", Remove
+		if fs != LocalFS {
+			overwrite, remove = "overwrite permanently", RemovePermanently
+		}
+		cs := []string{overwrite, "rename", "cancel"}
+		c, err := choose(cs)
+		if err != nil {
+			return "", err
+		}
+		switch c {
+		case overwrite:
+			var overwritten Operator
+			if info.IsDir() {
+				overwritten, err = newDir(dstPath, t.context, fs)
+			} else {
+				overwritten, err = newFile(dstPath, t.context, fs)
+			}
 			if err != nil {
-				return err
+				return "", err
 			}
-			switch c {
-			case "overwrite":
-				var dstOperator Operator
-				if info.IsDir() {
-					dstOperator, err = NewDir(dstPath, t.context)
-				} else {
-					dstOperator, err = NewFile(dstPath, t.context)
-				}
-				if err := Remove(dstOperator); err != nil {
-					return err
-				}
-			case "rename":
-				newName, err := rename(o)
-				if err != nil {
-					return err
-				}
-				dstPath = filepath.Join(dstDir, newName)
-			case "cancel":
-				continue
+			removeOverwritten = func() error {
+				return remove(overwritten)
 			}
+		case "rename":
+			newName, err := rename(o)
+			if err != nil {
+				return "", err
+			}
+			dstPath = filepath.Join(filepath.Dir(dstPath), newName)
+		case "cancel":
+			return "", nil
+		}
+	}
+	// The overwritten object is removed after the before hooks,
+	// so that a vetoed paste leaves it.
+	e := Event{Kind: EventPaste, Src: o.Path(), Dst: dstPath, FileSystem: fs}
+	if err := t.context.emit(e, func() error {
+		if err := removeOverwritten(); err != nil {
+			return err
+		}
+		return CopyToFS(o, fs, dstPath)
+	}); err != nil {
+		return "", err
+	}
+	return dstPath, nil
+}
+
+// Compare sets the view merging the two directories as root.
+// Each entry is marked as only in the left, only in the right,
+// identical or differing.
+func (t *Tree) Compare(left, right TextFunc, method CompareMethod, render RenderFunc) error {
+	defer t.Render(render)
+
+	l, err := left()
+	if err != nil {
+		return err
+	}
+	r, err := right()
+	if err != nil {
+		return err
+	}
+	if l, err = filepath.Abs(l); err != nil {
+		return err
+	}
+	if r, err = filepath.Abs(r); err != nil {
+		return err
+	}
+	c, err := Compare(l, r, method)
+	if err != nil {
+		return err
+	}
+	return t.SetRoot(NewCompareView(c, t.context))
+}
+
+// CopyToRight copies the differing entries from the left directory to the right
+// in the view of comparison.
+func (t *Tree) CopyToRight(cursor CursorFunc, choose ChooseFunc, rename OperatorTextFunc, setCursor SetCursorFunc, render RenderFunc) error {
+	return t.copyDifferences(cursor, true, choose, rename, setCursor, render)
+}
+
+// CopyToLeft copies the differing entries from the right directory to the left
+// in the view of comparison.
+func (t *Tree) CopyToLeft(cursor CursorFunc, choose ChooseFunc, rename OperatorTextFunc, setCursor SetCursorFunc, render RenderFunc) error {
+	return t.copyDifferences(cursor, false, choose, rename, setCursor, render)
+}
+
+func (t *Tree) copyDifferences(cursor CursorFunc, toRight bool, choose ChooseFunc, rename OperatorTextFunc, setCursor SetCursorFunc, render RenderFunc) error {
+	defer t.track(cursor).scanAndRender(setCursor, render)
+
+	var os Operators
+	if t.HasSelected() {
+		os = t.Selecteds()
+		defer os.Unselect()
+	} else {
+		o, err := t.Operator(cursor)
+		if err != nil {
+			return err
 		}
-		if o.IsDir() {
-			if err := shutil.CopyTree(o.Path(), dstPath, nil); err != nil {
+		os = Operators{o}
+	}
+	for _, o := range os {
+		c, ok := comparisonOf(o)
+		if !ok {
+			return fmt.Errorf("'%s' isn't in the view of comparison", o.Path())
+		}
+		rel, err := c.Rel(o)
+		if err != nil {
+			return err
+		}
+		if err := t.copyDifference(c, rel, toRight, choose, rename); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyDifference copies the entry at rel when it differs.
+// The directories existing on both sides are merged entry by entry.
+func (t *Tree) copyDifference(c *Comparison, rel string, toRight bool, choose ChooseFunc, rename OperatorTextFunc) error {
+	d := c.Difference(rel)
+	if d == Identical || d == OnlyRight && toRight || d == OnlyLeft && !toRight {
+		return nil
+	}
+	if c.bothDirs(rel) {
+		for _, child := range c.children[rel] {
+			if err := t.copyDifference(c, child, toRight, choose, rename); err != nil {
 				return err
 			}
-		} else {
-			if err := shutil.CopyFile(o.Path(), dstPath, true); err != nil {
-				return err
+		}
+		return nil
+	}
+	src, dst := c.Left, c.Right
+	if !toRight {
+		src, dst = dst, src
+	}
+	srcOperator, err := NewOperator(filepath.Join(src, filepath.FromSlash(rel)), t.context)
+	if err != nil {
+		return err
+	}
+	dstPath := filepath.Join(dst, filepath.FromSlash(rel))
+	if err := LocalFS.MkdirAll(filepath.Dir(dstPath)); err != nil {
+		return err
+	}
+	_, err = t.pasteTo(srcOperator, LocalFS, dstPath, choose, rename)
+	return err
+}
+
+func (t *Tree) Compress(cursor CursorFunc, text OperatorsTextFunc, cancel CancelFunc, setCursor SetCursorFunc, render RenderFunc) error {
+	tr := t.track(cursor)
+	defer tr.scanAndRender(setCursor, render)
+
+	var os Operators
+	if t.HasSelected() {
+		os = t.Selecteds()
+		defer os.Unselect()
+	} else {
+		o, err := t.Operator(cursor)
+		if err != nil {
+			return err
+		}
+		os = Operators{o}
+	}
+	name, err := text(os)
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		return cancel()
+	}
+	if !filepath.IsAbs(name) {
+		name = filepath.Join(os[0].Dirname(), name)
+	}
+	tr.target(LocalFS, name)
+	return Compress(os, name)
+}
+
+var errCanceled = errors.New("canceled")
+
+func (t *Tree) Extract(cursor CursorFunc, text OperatorTextFunc, choose ChooseFunc, cancel CancelFunc, setCursor SetCursorFunc, render RenderFunc) error {
+	tr := t.track(cursor)
+	defer tr.scanAndRender(setCursor, render)
+
+	o, err := t.Operator(cursor)
+	if err != nil {
+		return err
+	}
+	if ArchiveFormat(o.Name()) == "" {
+		return fmt.Errorf("'%s' isn't an archive", o.Path())
+	}
+	dstDir, err := text(o)
+	if err != nil {
+		return err
+	}
+	if dstDir == "" {
+		dstDir = ArchiveBase(o.Name())
+	}
+	if !filepath.IsAbs(dstDir) {
+		dstDir = filepath.Join(o.Dirname(), dstDir)
+	}
+	tr.target(LocalFS, dstDir)
+
+	var all string
+	err = Extract(o.Path(), dstDir, func(dstPath string) (bool, error) {
+		c := all
+		if c == "" {
+			cs := []string{"overwrite", "skip", "overwrite all", "skip all", "cancel"}
+			var err error
+			c, err = choose(cs)
+			if err != nil {
+				return false, err
+			}
+		}
+		switch c {
+		case "overwrite all":
+			all = "overwrite"
+			fallthrough
+		case "overwrite":
+			dstOperator, err := NewOperator(dstPath, t.context)
+			if err != nil {
+				return false, err
+			}
+			if err := Remove(dstOperator); err != nil {
+				return false, err
 			}
+			return true, nil
+		case "skip all":
+			all = "skip"
+			return false, nil
+		case "skip":
+			return false, nil
+		default:
+			return false, errCanceled
 		}
+	})
+	if err == errCanceled {
+		return cancel()
 	}
-	return nil
+	return err
+}
+
+func (t *Tree) loadBookmarks() (Bookmarks, error) {
+	return LoadBookmarks(t.context.Config.BookmarksFilename)
+}
+
+func (t *Tree) AddBookmark(cursor CursorFunc, text OperatorTextFunc) error {
+	o, err := t.Operator(cursor)
+	if err != nil {
+		return err
+	}
+	if !IsLocal(o) {
+		return fmt.Errorf("'%s' isn't on local disk", o.Path())
+	}
+	name, err := text(o)
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		name = o.Name()
+	}
+	bs, err := t.loadBookmarks()
+	if err != nil {
+		return err
+	}
+	return bs.Add(Bookmark{Name: name, Path: o.Path()}).Save(t.context.Config.BookmarksFilename)
+}
+
+func (t *Tree) Bookmarks(bookmarks BookmarksFunc) error {
+	bs, err := t.loadBookmarks()
+	if err != nil {
+		return err
+	}
+	return bookmarks(bs)
+}
+
+// JumpBookmark replaces the root under the cursor with the bookmarked directory.
+// When a file is bookmarked, its directory is set.
+// When the bookmarked object has disappeared, the nearest existing ancestor is set.
+func (t *Tree) JumpBookmark(cursor CursorFunc, choose ChooseFunc, cancel CancelFunc, render RenderFunc) error {
+	defer t.Render(render)
+
+	n, err := t.rootIndex(cursor)
+	if err != nil {
+		return err
+	}
+	bs, err := t.loadBookmarks()
+	if err != nil {
+		return err
+	}
+	name, err := choose(bs.Names())
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		return cancel()
+	}
+	b, ok := bs.Find(name)
+	if !ok {
+		return fmt.Errorf("bookmark '%s' isn't found", name)
+	}
+	p := b.Nearest()
+	if info, err := os.Stat(p); err == nil && !info.IsDir() {
+		p = filepath.Dir(p)
+	}
+	return t.SetRootPathAt(n, p)
+}
+
+func (t *Tree) DeleteBookmark(choose ChooseFunc, cancel CancelFunc) error {
+	bs, err := t.loadBookmarks()
+	if err != nil {
+		return err
+	}
+	name, err := choose(bs.Names())
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		return cancel()
+	}
+	return bs.Delete(name).Save(t.context.Config.BookmarksFilename)
+}
+
+// Visits passes the visited directories matching the query
+// ordered by frecency, for pickers to list them.
+// An empty query matches all directories.
+func (t *Tree) Visits(query TextFunc, visits VisitsFunc) error {
+	q, err := query()
+	if err != nil {
+		return err
+	}
+	vs, err := t.context.visited()
+	if err != nil {
+		return err
+	}
+	return visits(vs.Rank(q, time.Now()))
+}
+
+// Jump replaces the root under the cursor with the most frecent directory matching the query.
+// Directories which have disappeared are forgotten.
+func (t *Tree) Jump(cursor CursorFunc, query TextFunc, cancel CancelFunc, render RenderFunc) error {
+	defer t.Render(render)
+
+	n, err := t.rootIndex(cursor)
+	if err != nil {
+		return err
+	}
+	q, err := query()
+	if err != nil {
+		return err
+	}
+	if q == "" {
+		return cancel()
+	}
+	vs, err := t.context.visited()
+	if err != nil {
+		return err
+	}
+	for _, v := range vs.Rank(q, time.Now()) {
+		if info, err := os.Stat(v.Path); err == nil && info.IsDir() {
+			return t.SetRootPathAt(n, v.Path)
+		}
+		if err := t.context.forget(v.Path); err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("no visited directory matches '%s'", q)
+}
+
+// Preview passes the preview of the object at the cursor.
+func (t *Tree) Preview(cursor CursorFunc, preview PreviewFunc) error {
+	o, err := t.Operator(cursor)
+	if err != nil {
+		return err
+	}
+	c := t.context.Config
+	p, err := NewPreview(o, c.PreviewLines, c.PreviewBytes, c.PreviewFiles)
+	if err != nil {
+		return err
+	}
+	return preview(p)
+}
+
+// Export writes the tree in the format.
+func (t *Tree) Export(w io.Writer, format string, opts ExportOptions) error {
+	return Export(w, t.roots, format, opts)
 }
 
 func (t *Tree) Yank(cursor CursorFunc, setClipboard SetClipboardFunc) error {
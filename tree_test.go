@@ -1 +1,469 @@
 package tree_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	tree "github.com/minodisk/go-tree"
+)
+
+func newTree(t *testing.T, path string) (*tree.Tree, func()) {
+	dir, err := ioutil.TempDir("", "go-tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &tree.Context{Config: &tree.Config{
+		TrashDirname:      filepath.Join(dir, "trash"),
+		BookmarksFilename: filepath.Join(dir, "bookmarks.json"),
+		VisitsFilename:    filepath.Join(dir, "visits.json"),
+	}}
+	tr, err := tree.New(path, c)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return tr, func() { os.RemoveAll(dir) }
+}
+
+func cursorAt(i int) tree.CursorFunc {
+	return func() (int, error) { return i, nil }
+}
+
+func textOf(s string) tree.TextFunc {
+	return func() (string, error) { return s, nil }
+}
+
+func noRender([][]byte) error {
+	return nil
+}
+
+func TestWorkspace(t *testing.T) {
+	tr, clean := newTree(t, "./fixtures/foo")
+	defer clean()
+
+	if err := tr.AddRoot(textOf("./fixtures/sort"), noRender); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.AppendRootPath("./fixtures/foo"); err != nil {
+		t.Fatal(err)
+	}
+	if a, e := len(tr.Roots()), 2; a != e {
+		t.Fatalf("the tree should have %d roots, but has %d", e, a)
+	}
+
+	lines := tr.Lines()
+	foo := tr.Roots()[0].NumLines()
+	if a, e := len(lines), foo+tr.Roots()[1].NumLines(); a != e {
+		t.Errorf("Lines() should span all roots, expected %d rows, but actual %d", e, a)
+	}
+	if a, e := string(lines[foo]), "sort/"; a != e {
+		t.Errorf("the second root should be rendered as a top-level entry, expected '%s', but actual '%s'", e, a)
+	}
+	o, ok := tr.IndexOf(foo + 1)
+	if !ok || o.Dirname() != tr.Roots()[1].Path() {
+		t.Errorf("IndexOf() should span all roots")
+	}
+	if os := tr.ObjectsAt(tree.Range{Start: foo - 1, End: foo}); len(os) != 2 || os[1].Name() != "sort" {
+		t.Errorf("ObjectsAt() should span all roots, but returns %v", os)
+	}
+
+	if err := tr.RemoveRoot(cursorAt(foo+1), noRender); err != nil {
+		t.Fatal(err)
+	}
+	if a, e := len(tr.Roots()), 1; a != e {
+		t.Fatalf("RemoveRoot() should remove the root under the cursor, but %d roots remain", a)
+	}
+	if err := tr.RemoveRootAt(0); err == nil {
+		t.Errorf("the last root shouldn't be removed")
+	}
+}
+
+func TestReplaceRoot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-tree-root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, d := range []string{"a/bar/baz", "b/bb"} {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0775); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tr, clean := newTree(t, filepath.Join(dir, "a"))
+	defer clean()
+	for _, p := range []string{filepath.Join(dir, "b"), filepath.Join(dir, "a", "bar")} {
+		if err := tr.AppendRootPath(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	second := tr.Roots()[0].NumLines()
+
+	if err := tr.CD(cursorAt(second+1), textOf(filepath.Join(dir, "a", "bar", "baz")), noRender); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Home(cursorAt(0), noRender); err != nil {
+		t.Fatal(err)
+	}
+	home, err := tree.DirHome()
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, r := range tr.Roots() {
+		names = append(names, r.Path())
+	}
+	if len(names) != 3 || names[0] != home || filepath.Base(names[1]) != "baz" || filepath.Base(names[2]) != "bar" {
+		t.Errorf("CD() and Home() should replace only the root under the cursor, but the roots are %v", names)
+	}
+
+	if err := tr.SetRootPath(filepath.Join(dir, "a")); err != nil {
+		t.Fatal(err)
+	}
+	if a := len(tr.Roots()); a != 1 {
+		t.Errorf("SetRootPath() should replace all roots, but %d roots remain", a)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-tree-history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, d := range []string{"a/aa", "b"} {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0775); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tr, clean := newTree(t, dir)
+	defer clean()
+
+	if err := tr.Toggle(cursorAt(1), noRender); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.CD(cursorAt(1), textOf(filepath.Join(dir, "b")), noRender); err != nil {
+		t.Fatal(err)
+	}
+
+	var cursor int
+	setCursor := func(c int) error {
+		cursor = c
+		return nil
+	}
+	if err := tr.Back(cursorAt(0), setCursor, noRender); err != nil {
+		t.Fatal(err)
+	}
+	a := linesToString(tr.Lines())
+	e := filepath.Base(dir) + `/
+- a/
+ + aa/
++ b/`
+	if a != e {
+		t.Errorf("Back() should restore the root with the opened directories\nexpected:\n%s\nactual:\n%s", e, a)
+	}
+	if cursor != 1 {
+		t.Errorf("Back() should restore the cursor, expected 1, but actual %d", cursor)
+	}
+
+	if err := tr.Forward(cursorAt(0), setCursor, noRender); err != nil {
+		t.Fatal(err)
+	}
+	if a, e := tr.Roots()[0].Name(), "b"; a != e {
+		t.Errorf("Forward() should restore the root '%s', but actual '%s'", e, a)
+	}
+	if err := tr.Forward(cursorAt(0), setCursor, noRender); err != nil {
+		t.Fatal(err)
+	}
+	if a, e := tr.Roots()[0].Name(), "b"; a != e {
+		t.Errorf("Forward() without the history shouldn't change the root")
+	}
+}
+
+func TestReveal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-tree-reveal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, d := range []string{"a/aa/aaa", "a/ab", "b/ba"} {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0775); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tr, clean := newTree(t, filepath.Join(dir, "a"))
+	defer clean()
+
+	i, err := tr.Reveal(filepath.Join(dir, "a", "aa", "aaa"), tree.RevealFail, noRender)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i != 2 {
+		t.Errorf("Reveal() should return the row of the path, expected 2, but actual %d", i)
+	}
+	a := linesToString(tr.Lines())
+	e := `a/
+- aa/
+ + aaa/
++ ab/`
+	if a != e {
+		t.Errorf("Reveal() should open the ancestors\nexpected:\n%s\nactual:\n%s", e, a)
+	}
+
+	target := filepath.Join(dir, "b", "ba")
+	if _, err := tr.Reveal(target, tree.RevealFail, noRender); err == nil {
+		t.Errorf("Reveal() with RevealFail should fail for the path outside the roots")
+	}
+	if i, err = tr.Reveal(target, tree.RevealAppend, noRender); err != nil {
+		t.Fatal(err)
+	}
+	if a, e := len(tr.Roots()), 2; a != e {
+		t.Fatalf("Reveal() with RevealAppend should append a root, expected %d roots, but actual %d", e, a)
+	}
+	if i != 5 {
+		t.Errorf("Reveal() should return the row in the appended root, expected 5, but actual %d", i)
+	}
+	if i, err = tr.Reveal(filepath.Join(dir, "a", "ab"), tree.RevealParent, noRender); err != nil {
+		t.Fatal(err)
+	}
+	if a, e := len(tr.Roots()), 2; a != e || i != 3 {
+		t.Errorf("Reveal() shouldn't re-root the path inside the roots, expected %d roots at row 3, but actual %d roots at row %d", e, a, i)
+	}
+	if _, err := tr.Reveal(filepath.Join(dir, "gone"), tree.RevealParent, noRender); err == nil {
+		t.Errorf("Reveal() should fail for the path which doesn't exist")
+	}
+}
+
+func TestRevealRelativeRoot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-tree-reveal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, d := range []string{"a/aa/aaa", "a/ab"} {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0775); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if err := os.Chdir(filepath.Join(dir, "a")); err != nil {
+		t.Fatal(err)
+	}
+
+	tr, clean := newTree(t, ".")
+	defer clean()
+	if i, err := tr.Reveal(filepath.Join("aa", "aaa"), tree.RevealFail, noRender); err != nil || i != 2 {
+		t.Errorf("Reveal() should find the path in the root '.', but returns %d, %v", i, err)
+	}
+
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	tr, clean = newTree(t, "./a")
+	defer clean()
+	i, err := tr.Reveal(filepath.Join("a", "ab"), tree.RevealParent, noRender)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, e := tr.Roots()[0].Path(), "a"; a != e || i != 2 {
+		t.Errorf("Reveal() should open the relative root '%s' at row 2, but root '%s' at row %d", e, a, i)
+	}
+}
+
+func TestCursorTracking(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-tree-cursor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.MkdirAll(filepath.Join(dir, "d", "dd"), 0775); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{"a.txt", "b.txt", "c.txt"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, f), []byte{}, 0664); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tr, clean := newTree(t, dir)
+	defer clean()
+
+	cursor := -1
+	setCursor := func(c int) error {
+		cursor = c
+		return nil
+	}
+	rename := func(tree.Operator) (string, error) { return "z.txt", nil }
+	if err := tr.Rename(cursorAt(2), rename, nil, nil, setCursor, noRender); err != nil {
+		t.Fatal(err)
+	}
+	if cursor != 4 {
+		t.Errorf("Rename() should set the cursor on the renamed object, expected 4, but actual %d", cursor)
+	}
+
+	if err := tr.Toggle(cursorAt(1), noRender); err != nil {
+		t.Fatal(err)
+	}
+	yes := func(...tree.Operator) (bool, error) { return true, nil }
+	if err := tr.RemovePermanently(cursorAt(1), yes, nil, setCursor, noRender); err != nil {
+		t.Fatal(err)
+	}
+	if cursor != 1 {
+		t.Errorf("RemovePermanently() should set the cursor on the following neighbour, expected 1, but actual %d", cursor)
+	}
+	if o, _ := tr.IndexOf(cursor); o.Name() != "b.txt" {
+		t.Errorf("RemovePermanently() should skip the removed descendants, but the cursor is on '%s'", o.Name())
+	}
+
+	if err := tr.RemovePermanently(cursorAt(3), yes, nil, setCursor, noRender); err != nil {
+		t.Fatal(err)
+	}
+	if cursor != 2 {
+		t.Errorf("RemovePermanently() should set the cursor on the preceding neighbour at the end, expected 2, but actual %d", cursor)
+	}
+}
+
+func TestSelection(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-tree-selection")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.MkdirAll(filepath.Join(dir, "d", "dd"), 0775); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{"a.go", "b.txt", "d/c.go", "d/dd/e.go"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, f), []byte{}, 0664); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tr, clean := newTree(t, dir)
+	defer clean()
+
+	os := tr.ObjectsAt(tree.Range{Start: 2, End: 3})
+	if len(os) != 2 || os[0].Name() != "a.go" || os[1].Name() != "b.txt" {
+		t.Fatalf("ObjectsAt() should return the files at the rows, but returns %v", os)
+	}
+
+	n, err := tr.SelectRange(func() (tree.Range, error) { return tree.Range{Start: 1, End: 2}, nil }, noRender)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("SelectRange() should select 2 objects, but selects %d", n)
+	}
+	if n, err = tr.ClearSelection(noRender); err != nil || n != 2 {
+		t.Errorf("ClearSelection() should unselect 2 objects, but unselects %d: %v", n, err)
+	}
+	if n, err = tr.SelectAll(cursorAt(2), noRender); err != nil || n != 3 {
+		t.Errorf("SelectAll() should select the siblings, expected 3, but actual %d: %v", n, err)
+	}
+	tr.ClearSelection(noRender)
+
+	if n, err = tr.SelectGlob(cursorAt(0), textOf("*.go"), tree.SelectShown, noRender); err != nil || n != 1 {
+		t.Errorf("SelectGlob() should select the shown objects, expected 1, but actual %d: %v", n, err)
+	}
+	if n, err = tr.SelectGlob(cursorAt(0), textOf("*.go"), tree.SelectDescendants, noRender); err != nil || n != 2 {
+		t.Errorf("SelectGlob() should select all descendants, expected 2, but actual %d: %v", n, err)
+	}
+	a := linesToString(tr.Lines())
+	e := filepath.Base(dir) + `/
+- d/
+ - dd/
+  * e.go
+ * c.go
+* a.go
+| b.txt`
+	if a != e {
+		t.Errorf("SelectGlob() should open the directories containing the matched objects\nexpected:\n%s\nactual:\n%s", e, a)
+	}
+	tr.ClearSelection(noRender)
+
+	if n, err = tr.SelectRegexp(cursorAt(1), textOf(`^dd/`), tree.SelectShown, noRender); err != nil || n != 1 {
+		t.Errorf("SelectRegexp() should match the relative paths, expected 1, but actual %d: %v", n, err)
+	}
+	if _, err = tr.SelectRegexp(cursorAt(1), textOf(`(`), tree.SelectShown, noRender); err == nil {
+		t.Errorf("SelectRegexp() should fail with the invalid pattern")
+	}
+}
+
+func TestSelectionSurvives(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-tree-selection")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.MkdirAll(filepath.Join(dir, "d"), 0775); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{"a.txt", "d/b.txt"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, f), []byte{}, 0664); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tr, clean := newTree(t, dir)
+	defer clean()
+
+	if err := tr.Toggle(cursorAt(1), noRender); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tr.SelectRange(func() (tree.Range, error) { return tree.Range{Start: 2, End: 3}, nil }, noRender); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Toggle(cursorAt(1), noRender); err != nil {
+		t.Fatal(err)
+	}
+	if a, e := len(tr.Selecteds()), 2; a != e {
+		t.Errorf("Selecteds() should include the objects in the closed directory, expected %d, but actual %d", e, a)
+	}
+	var hidden tree.Operators
+	if err := tr.HiddenSelecteds(func(os tree.Operators) error {
+		hidden = os
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(hidden) != 1 || hidden[0].Name() != "b.txt" {
+		t.Errorf("HiddenSelecteds() should pass the objects in the closed directory, but passes %v", hidden)
+	}
+
+	if err := tr.CD(cursorAt(0), textOf(filepath.Join(dir, "d")), noRender); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Scan(); err != nil {
+		t.Fatal(err)
+	}
+	a := linesToString(tr.Lines())
+	e := `d/
+* b.txt`
+	if a != e {
+		t.Errorf("the selection should survive changing the root\nexpected:\n%s\nactual:\n%s", e, a)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "a.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := tr.ClearSelection(noRender); err != nil || n != 1 {
+		t.Errorf("ClearSelection() should forget the disappeared objects, expected 1, but actual %d: %v", n, err)
+	}
+
+	if err := tr.Select(cursorAt(1), func(int) error { return nil }, noRender); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.CD(cursorAt(0), textOf(dir), noRender); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, "d", "b.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if !tr.HasSelected() {
+		t.Errorf("HasSelected() shouldn't check the existence of the hidden objects")
+	}
+	if a := len(tr.Selecteds()); a != 0 || tr.HasSelected() {
+		t.Errorf("Selecteds() should forget the disappeared objects, but returns %d", a)
+	}
+}